@@ -0,0 +1,133 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// AdaptiveLimiter implements the Resilience interface. It limits concurrent
+// executions like Bulkhead, but the limit is found by a LimitAlgorithm from
+// latency and drop signals. Calls over the limit are rejected immediately
+// with a BulkheadRejectedError, like a Bulkhead without a queue.
+type AdaptiveLimiter struct {
+	algorithm LimitAlgorithm
+	isDrop    func(error) bool
+
+	mutex    sync.Mutex
+	limit    int
+	inFlight int
+	rejected uint64
+
+	onRejected OnBulkheadRejectedFunc
+
+	recoverPanics bool
+}
+
+// NewAdaptiveLimiter creates an adaptive concurrency limiter policy
+func NewAdaptiveLimiter(algorithm LimitAlgorithm) *AdaptiveLimiter {
+	return &AdaptiveLimiter{
+		algorithm: algorithm,
+		isDrop:    isOverloadError,
+		limit:     algorithm.Limit(),
+	}
+}
+
+// isOverloadError is the default drop predicate: timeouts and rejections
+// signal that the dependency is overloaded.
+func isOverloadError(err error) bool {
+	return errors.Is(err, ErrTimeout) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrBulkheadRejected)
+}
+
+// Handle configures which errors count as drops (overload signals).
+// 默认超时和被拒绝计为过载。
+func (l *AdaptiveLimiter) Handle(f func(error) bool) *AdaptiveLimiter {
+	l.isDrop = f
+	return l
+}
+
+// OnRejected sets the callback for rejected calls
+func (l *AdaptiveLimiter) OnRejected(fn OnBulkheadRejectedFunc) *AdaptiveLimiter {
+	l.onRejected = fn
+	return l
+}
+
+// Limit returns the current concurrency limit
+func (l *AdaptiveLimiter) Limit() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.limit
+}
+
+// InFlight returns the number of executions currently running
+func (l *AdaptiveLimiter) InFlight() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.inFlight
+}
+
+// Rejected returns the total number of rejected calls
+func (l *AdaptiveLimiter) Rejected() uint64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.rejected
+}
+
+// RecoverPanics converts panics in fn into a *PanicError, see Recover
+func (l *AdaptiveLimiter) RecoverPanics() *AdaptiveLimiter {
+	l.recoverPanics = true
+	return l
+}
+
+// Execute runs fn if the current limit allows it
+func (l *AdaptiveLimiter) Execute(ctx context.Context, fn Func) (err error) {
+	if l.recoverPanics {
+		fn = recoverFunc(fn)
+	}
+
+	l.mutex.Lock()
+	if l.inFlight >= l.limit {
+		l.rejected++
+		err := &BulkheadRejectedError{
+			Reason:   RejectQueueFull,
+			InFlight: l.inFlight,
+			Err:      ErrBulkheadRejected,
+		}
+		l.mutex.Unlock()
+
+		if l.onRejected != nil {
+			l.onRejected(ctx)
+		}
+		return err
+	}
+	l.inFlight++
+	inFlight := l.inFlight
+	l.mutex.Unlock()
+
+	start := time.Now()
+	defer func() { l.complete(inFlight, time.Since(start), err) }()
+
+	return fn(ctx)
+}
+
+// complete releases the slot and feeds the sample to the algorithm
+func (l *AdaptiveLimiter) complete(inFlight int, rtt time.Duration, err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.inFlight--
+
+	// 调用方主动取消，不作为样本
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	l.limit = l.algorithm.Update(LimitSample{
+		RTT:      rtt,
+		InFlight: inFlight,
+		Dropped:  err != nil && l.isDrop(err),
+	})
+}
@@ -0,0 +1,141 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 超过当前 limit 立即拒绝
+func TestAdaptiveLimiter_Rejects(t *testing.T) {
+	l := NewAdaptiveLimiter(NewAIMDLimit(AIMDLimitOptions{InitialLimit: 2, MaxLimit: 2}))
+
+	var rejected int
+	l.OnRejected(func(ctx context.Context) { rejected++ })
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = l.Execute(context.Background(), func(ctx context.Context) error {
+				started <- struct{}{}
+				<-release
+				return nil
+			})
+		}()
+	}
+	<-started
+	<-started
+
+	if l.InFlight() != 2 {
+		t.Fatalf("expected 2 in flight, got %d", l.InFlight())
+	}
+
+	err := l.Execute(context.Background(), func(ctx context.Context) error { return nil })
+	if !errors.Is(err, ErrBulkheadRejected) {
+		t.Fatalf("expected ErrBulkheadRejected, got %v", err)
+	}
+	if rejected != 1 || l.Rejected() != 1 {
+		t.Fatalf("expected 1 rejection")
+	}
+
+	close(release)
+	wg.Wait()
+}
+
+// AIMD：过载时乘性缩减，成功时加性增长
+func TestAIMDLimit(t *testing.T) {
+	a := NewAIMDLimit(AIMDLimitOptions{InitialLimit: 10, MinLimit: 2, MaxLimit: 12})
+
+	if got := a.Update(LimitSample{RTT: time.Millisecond, InFlight: 10}); got != 11 {
+		t.Fatalf("expected 11, got %d", got)
+	}
+	// 低负载不增长
+	if got := a.Update(LimitSample{RTT: time.Millisecond, InFlight: 1}); got != 11 {
+		t.Fatalf("expected 11, got %d", got)
+	}
+	if got := a.Update(LimitSample{RTT: time.Millisecond, InFlight: 11, Dropped: true}); got != 9 {
+		t.Fatalf("expected 9, got %d", got)
+	}
+	for i := 0; i < 20; i++ {
+		a.Update(LimitSample{Dropped: true})
+	}
+	if a.Limit() != 2 {
+		t.Fatalf("expected MinLimit, got %d", a.Limit())
+	}
+}
+
+// Vegas：耗时显著上升时缩减
+func TestVegasLimit(t *testing.T) {
+	v := NewVegasLimit(VegasLimitOptions{InitialLimit: 20})
+
+	v.Update(LimitSample{RTT: 10 * time.Millisecond, InFlight: 20})
+	if got := v.Update(LimitSample{RTT: 10 * time.Millisecond, InFlight: 20}); got <= 20 {
+		t.Fatalf("expected growth without queueing, got %d", got)
+	}
+
+	before := v.Limit()
+	if got := v.Update(LimitSample{RTT: 100 * time.Millisecond, InFlight: before}); got >= before {
+		t.Fatalf("expected shrink under queueing, got %d (before %d)", got, before)
+	}
+
+	// 耗时更短的过载信号仍然缩减，且不刷新无负载耗时
+	before = v.Limit()
+	if got := v.Update(LimitSample{RTT: time.Millisecond, InFlight: before, Dropped: true}); got >= before {
+		t.Fatalf("expected shrink on drop, got %d (before %d)", got, before)
+	}
+	if v.rttNoLoad != 10*time.Millisecond {
+		t.Fatalf("drop should not update no-load RTT, got %v", v.rttNoLoad)
+	}
+}
+
+// Gradient2：耗时上升时缩减
+func TestGradient2Limit(t *testing.T) {
+	g := NewGradient2Limit(Gradient2LimitOptions{InitialLimit: 50})
+
+	for i := 0; i < 20; i++ {
+		g.Update(LimitSample{RTT: 10 * time.Millisecond, InFlight: 50})
+	}
+	before := g.Limit()
+
+	for i := 0; i < 20; i++ {
+		g.Update(LimitSample{RTT: 100 * time.Millisecond, InFlight: 50})
+	}
+	if g.Limit() >= before {
+		t.Fatalf("expected shrink under latency increase, got %d (before %d)", g.Limit(), before)
+	}
+}
+
+// Gradient2：快速失败的过载信号使 limit 下降，而不是被当作低耗时
+func TestGradient2Limit_Dropped(t *testing.T) {
+	g := NewGradient2Limit(Gradient2LimitOptions{InitialLimit: 50})
+	for i := 0; i < 20; i++ {
+		g.Update(LimitSample{RTT: 10 * time.Millisecond, InFlight: 50})
+	}
+	before := g.Limit()
+
+	for i := 0; i < 50; i++ {
+		g.Update(LimitSample{RTT: time.Millisecond, InFlight: g.Limit(), Dropped: true})
+	}
+	if g.Limit() >= before {
+		t.Fatalf("expected shrink on drops, got %d (before %d)", g.Limit(), before)
+	}
+}
+
+// 超时计为过载信号，limit 下降
+func TestAdaptiveLimiter_DropShrinksLimit(t *testing.T) {
+	l := NewAdaptiveLimiter(NewAIMDLimit(AIMDLimitOptions{InitialLimit: 10}))
+
+	_ = l.Execute(context.Background(), func(ctx context.Context) error {
+		return ErrTimeout
+	})
+
+	if l.Limit() != 9 {
+		t.Fatalf("expected limit 9, got %d", l.Limit())
+	}
+}
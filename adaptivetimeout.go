@@ -0,0 +1,131 @@
+package resilience
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// AdaptiveTimeoutOptions configures a timeout derived from observed latency.
+// 超时 = 调用延迟的 Percentile 分位数 × Multiplier，并限制在 [Min, Max] 内。
+// 超时的调用按超时时间计入样本，因此 Multiplier 必须大于 1，超时才能回升。
+type AdaptiveTimeoutOptions struct {
+	Percentile float64       // 分位数，例如 99.5；默认 99
+	Multiplier float64       // 放大倍数，必须 > 1；默认 1.5
+	Min        time.Duration // 下限；0 表示不限制
+	Max        time.Duration // 上限；0 表示不限制
+
+	Warmup     time.Duration // 样本不足时使用的超时；默认为 Max
+	MinSamples int           // 启用自适应所需的最少样本数；默认 100
+	Window     int           // 样本窗口，达到后旧样本衰减一半；默认 10000
+}
+
+// adaptiveTimeout tracks successful call latency for a Timeout policy
+type adaptiveTimeout struct {
+	opts      AdaptiveTimeoutOptions
+	histogram *latencyHistogram
+	current   atomic.Int64 // 当前生效的超时
+}
+
+func newAdaptiveTimeout(opts AdaptiveTimeoutOptions) *adaptiveTimeout {
+	if opts.Percentile <= 0 || opts.Percentile > 100 {
+		opts.Percentile = 99
+	}
+	if opts.Multiplier == 0 {
+		opts.Multiplier = 1.5
+	}
+	if opts.Multiplier <= 1 {
+		panic("adaptive timeout Multiplier must be > 1")
+	}
+	if opts.Warmup <= 0 {
+		if opts.Max <= 0 {
+			panic("adaptive timeout requires Warmup or Max")
+		}
+		opts.Warmup = opts.Max
+	}
+	if opts.MinSamples <= 0 {
+		opts.MinSamples = 100
+	}
+	if opts.Window <= 0 {
+		opts.Window = 10000
+	}
+	if opts.Max > 0 && opts.Min > opts.Max {
+		panic("adaptive timeout Min must be <= Max")
+	}
+
+	a := &adaptiveTimeout{
+		opts:      opts,
+		histogram: newLatencyHistogram(opts.Window),
+	}
+	a.current.Store(int64(opts.Warmup))
+	return a
+}
+
+// NewAdaptiveTimeout creates a timeout policy that adapts to observed latency
+func NewAdaptiveTimeout(opts AdaptiveTimeoutOptions) *Timeout {
+	return NewTimeout(0).WithAdaptive(opts)
+}
+
+// WithAdaptive derives the timeout from the latency of successful and
+// timed-out calls.
+// A timeout generator, if configured, still takes precedence.
+func (t *Timeout) WithAdaptive(opts AdaptiveTimeoutOptions) *Timeout {
+	t.adaptive = newAdaptiveTimeout(opts)
+	return t
+}
+
+// CurrentTimeout returns the timeout the next execution would use when no
+// generator is configured. 用于监控自适应超时的当前值。
+func (t *Timeout) CurrentTimeout() time.Duration {
+	if t.adaptive != nil {
+		return t.adaptive.timeout()
+	}
+	return t.timeout
+}
+
+// LatencySamples returns the number of latency samples held by an adaptive
+// timeout, or 0 if the policy is not adaptive.
+func (t *Timeout) LatencySamples() int {
+	if t.adaptive == nil {
+		return 0
+	}
+	return int(t.adaptive.histogram.Count())
+}
+
+func (a *adaptiveTimeout) timeout() time.Duration {
+	return time.Duration(a.current.Load())
+}
+
+// observe records the latency of an execution. A call cut off by this
+// policy is recorded as at least the timeout it hit; recording successes
+// only would cut the data off at the current timeout and let it drift
+// below the configured percentile. Other failures are not recorded.
+func (a *adaptiveTimeout) observe(err error, elapsed, timeout time.Duration) {
+	switch {
+	case err == nil:
+		a.record(elapsed)
+	case timeout > 0 && errors.Is(err, ErrTimeout):
+		if elapsed < timeout {
+			// 内层策略的超时，不是本策略触发的
+			return
+		}
+		a.record(elapsed)
+	}
+}
+
+func (a *adaptiveTimeout) record(d time.Duration) {
+	a.histogram.Record(d)
+
+	if a.histogram.Count() < uint64(a.opts.MinSamples) {
+		return
+	}
+
+	timeout := time.Duration(float64(a.histogram.Quantile(a.opts.Percentile/100)) * a.opts.Multiplier)
+	if a.opts.Min > 0 && timeout < a.opts.Min {
+		timeout = a.opts.Min
+	}
+	if a.opts.Max > 0 && timeout > a.opts.Max {
+		timeout = a.opts.Max
+	}
+	a.current.Store(int64(timeout))
+}
@@ -0,0 +1,146 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 直方图分位数
+func TestLatencyHistogram_Quantile(t *testing.T) {
+	h := newLatencyHistogram(0)
+	for i := 1; i <= 100; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	p50 := h.Quantile(0.5)
+	if p50 < 50*time.Millisecond || p50 > 55*time.Millisecond {
+		t.Fatalf("unexpected p50: %v", p50)
+	}
+	p99 := h.Quantile(0.99)
+	if p99 < 99*time.Millisecond || p99 > 109*time.Millisecond {
+		t.Fatalf("unexpected p99: %v", p99)
+	}
+}
+
+// 窗口满后样本衰减
+func TestLatencyHistogram_Window(t *testing.T) {
+	h := newLatencyHistogram(10)
+	for i := 0; i < 10; i++ {
+		h.Record(time.Millisecond)
+	}
+	if c := h.Count(); c != 5 {
+		t.Fatalf("expected 5 samples after decay, got %d", c)
+	}
+}
+
+// 预热阶段使用默认超时，样本足够后按分位数计算
+func TestTimeout_Adaptive(t *testing.T) {
+	to := NewAdaptiveTimeout(AdaptiveTimeoutOptions{
+		Percentile: 99,
+		Multiplier: 2,
+		Min:        5 * time.Millisecond,
+		Max:        time.Second,
+		Warmup:     500 * time.Millisecond,
+		MinSamples: 10,
+	})
+
+	if to.CurrentTimeout() != 500*time.Millisecond {
+		t.Fatalf("expected warm-up timeout, got %v", to.CurrentTimeout())
+	}
+
+	for i := 0; i < 10; i++ {
+		_ = to.Execute(context.Background(), func(ctx context.Context) error {
+			time.Sleep(time.Millisecond)
+			return nil
+		})
+	}
+
+	if to.LatencySamples() != 10 {
+		t.Fatalf("expected 10 samples, got %d", to.LatencySamples())
+	}
+
+	current := to.CurrentTimeout()
+	if current >= 500*time.Millisecond || current < 5*time.Millisecond {
+		t.Fatalf("unexpected adaptive timeout: %v", current)
+	}
+
+	// 失败调用不计入样本
+	_ = to.Execute(context.Background(), func(ctx context.Context) error {
+		return errors.New("fail")
+	})
+	if to.LatencySamples() != 10 {
+		t.Fatalf("failed call should not be recorded")
+	}
+}
+
+// 自适应超时生效
+func TestTimeout_Adaptive_Timeout(t *testing.T) {
+	to := NewAdaptiveTimeout(AdaptiveTimeoutOptions{
+		Min:        10 * time.Millisecond,
+		Warmup:     time.Second,
+		MinSamples: 5,
+	}).WithMode(Pessimistic)
+
+	for i := 0; i < 5; i++ {
+		_ = to.Execute(context.Background(), func(ctx context.Context) error { return nil })
+	}
+
+	if to.CurrentTimeout() != 10*time.Millisecond {
+		t.Fatalf("expected Min bound, got %v", to.CurrentTimeout())
+	}
+
+	err := to.Execute(context.Background(), func(ctx context.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+}
+
+// 超时的调用按超时时间计入样本，超时可以回升
+func TestTimeout_Adaptive_RecordsTimeouts(t *testing.T) {
+	to := NewAdaptiveTimeout(AdaptiveTimeoutOptions{
+		Multiplier: 2,
+		Warmup:     10 * time.Millisecond,
+		MinSamples: 3,
+	})
+
+	for i := 0; i < 3; i++ {
+		err := to.Execute(context.Background(), func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		if !errors.Is(err, ErrTimeout) {
+			t.Fatalf("expected ErrTimeout, got %v", err)
+		}
+	}
+
+	if to.LatencySamples() != 3 {
+		t.Fatalf("timed-out calls should be recorded, got %d samples", to.LatencySamples())
+	}
+	if current := to.CurrentTimeout(); current < 20*time.Millisecond {
+		t.Fatalf("expected timeout to grow, got %v", current)
+	}
+}
+
+// 非法配置直接 panic，Warmup 默认取 Max
+func TestTimeout_Adaptive_Options(t *testing.T) {
+	expectPanic := func(name string, opts AdaptiveTimeoutOptions) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("%s: expected panic", name)
+			}
+		}()
+		NewAdaptiveTimeout(opts)
+	}
+	expectPanic("multiplier", AdaptiveTimeoutOptions{Multiplier: 1, Warmup: time.Second})
+	expectPanic("warmup", AdaptiveTimeoutOptions{})
+
+	to := NewAdaptiveTimeout(AdaptiveTimeoutOptions{Max: 2 * time.Second})
+	if to.CurrentTimeout() != 2*time.Second {
+		t.Fatalf("expected Warmup to default to Max, got %v", to.CurrentTimeout())
+	}
+}
@@ -1,26 +1,196 @@
 package resilience
 
 import (
+	"container/list"
 	"context"
 	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var ErrBulkheadRejected = errors.New("bulkhead limit exceeded")
 
+// ErrBulkheadQueueTimeout is returned when a call waited in the queue longer
+// than the configured maximum. It matches ErrBulkheadRejected with errors.Is.
+var ErrBulkheadQueueTimeout = fmt.Errorf("%w: max queue wait exceeded", ErrBulkheadRejected)
+
+// ErrBulkheadEvicted is returned to a queued call that was evicted to make
+// room for a higher priority call. It matches ErrBulkheadRejected with errors.Is.
+var ErrBulkheadEvicted = fmt.Errorf("%w: evicted by higher priority call", ErrBulkheadRejected)
+
+// ErrBulkheadClosed is returned to calls arriving after Close, and to queued
+// calls failed by Close. It matches ErrBulkheadRejected with errors.Is.
+var ErrBulkheadClosed = fmt.Errorf("%w: bulkhead closed", ErrBulkheadRejected)
+
+// ErrBulkheadShed is returned to a queued call dropped by the CoDel queue
+// discipline. It matches ErrBulkheadRejected with errors.Is.
+var ErrBulkheadShed = fmt.Errorf("%w: shed from congested queue", ErrBulkheadRejected)
+
+// ErrBulkheadDeadline is returned to a queued call whose ctx deadline leaves
+// less time than a typical execution. It matches ErrBulkheadRejected.
+var ErrBulkheadDeadline = fmt.Errorf("%w: deadline too short to complete", ErrBulkheadRejected)
+
+// ErrBulkheadPartitionLimit is returned by a PartitionedBulkhead when a new
+// key arrives while every tracked partition has calls. It matches
+// ErrBulkheadRejected with errors.Is.
+var ErrBulkheadPartitionLimit = fmt.Errorf("%w: too many partitions", ErrBulkheadRejected)
+
+// BulkheadRejectReason describes why a call was rejected
+type BulkheadRejectReason int
+
+const (
+	// RejectQueueFull means no execution slot and no queue slot were available
+	RejectQueueFull BulkheadRejectReason = iota
+
+	// RejectQueueTimeout means the call waited longer than the max queue wait
+	RejectQueueTimeout
+
+	// RejectEvicted means the queued call was evicted by a higher priority call
+	RejectEvicted
+
+	// RejectClosed means the bulkhead was closed
+	RejectClosed
+
+	// RejectShed means the queued call was dropped by the CoDel discipline
+	RejectShed
+
+	// RejectDeadline means the call's deadline could not be met
+	RejectDeadline
+
+	// RejectCancelled means the call's ctx was done while it was queued
+	RejectCancelled
+
+	// RejectPartitionLimit means a PartitionedBulkhead could not track a new key
+	RejectPartitionLimit
+)
+
+func (r BulkheadRejectReason) String() string {
+	switch r {
+	case RejectQueueFull:
+		return "QueueFull"
+	case RejectQueueTimeout:
+		return "QueueTimeout"
+	case RejectEvicted:
+		return "Evicted"
+	case RejectClosed:
+		return "Closed"
+	case RejectShed:
+		return "Shed"
+	case RejectDeadline:
+		return "Deadline"
+	case RejectCancelled:
+		return "Cancelled"
+	case RejectPartitionLimit:
+		return "PartitionLimit"
+	default:
+		return "Unknown"
+	}
+}
+
+// BulkheadClosePolicy decides what Close does with queued calls
+type BulkheadClosePolicy int
+
+const (
+	// CloseRejectQueued fails queued calls with ErrBulkheadClosed
+	CloseRejectQueued BulkheadClosePolicy = iota
+
+	// CloseDrainQueued lets queued calls run before Close completes
+	CloseDrainQueued
+)
+
+// BulkheadRejectedError is returned for every call a bulkhead did not run.
+// It matches ErrBulkheadRejected with errors.Is, and unwraps to the error
+// specific to the reason: ErrBulkheadQueueTimeout, ErrBulkheadClosed, ...,
+// or the ctx error for RejectCancelled.
+type BulkheadRejectedError struct {
+	Reason   BulkheadRejectReason
+	InFlight int // 拒绝时执行中的调用数
+	Queued   int // 拒绝时排队中的调用数
+	Err      error
+}
+
+func (e *BulkheadRejectedError) Error() string {
+	return fmt.Sprintf("%s (reason %s, in-flight %d, queued %d)", e.Err, e.Reason, e.InFlight, e.Queued)
+}
+
+func (e *BulkheadRejectedError) Unwrap() error {
+	return e.Err
+}
+
+func (e *BulkheadRejectedError) Is(target error) bool {
+	return target == ErrBulkheadRejected
+}
+
+// rejectReasonErr returns the error specific to a rejection reason
+func rejectReasonErr(ctx context.Context, reason BulkheadRejectReason) error {
+	switch reason {
+	case RejectQueueTimeout:
+		return ErrBulkheadQueueTimeout
+	case RejectEvicted:
+		return ErrBulkheadEvicted
+	case RejectClosed:
+		return ErrBulkheadClosed
+	case RejectShed:
+		return ErrBulkheadShed
+	case RejectDeadline:
+		return ErrBulkheadDeadline
+	case RejectCancelled:
+		return ctx.Err()
+	case RejectPartitionLimit:
+		return ErrBulkheadPartitionLimit
+	default:
+		return ErrBulkheadRejected
+	}
+}
+
 type OnBulkheadRejectedFunc func(ctx context.Context)
 
-// Bulkhead implements the Resilience interface
+type OnBulkheadRejectedReasonFunc func(ctx context.Context, reason BulkheadRejectReason)
+
+// Bulkhead implements the Resilience interface.
+// Queued calls are admitted highest priority first (see WithPriority), and
+// in strict arrival order among equal priorities.
 type Bulkhead struct {
-	maxParallel int // max concurrent executions
-	maxQueue    int // max queued executions
+	maxParallel  int           // max concurrent executions, guarded by mutex
+	maxQueue     int           // max queued executions, guarded by mutex
+	maxQueueWait time.Duration // max time spent in queue, 0 means unlimited
+
+	priorityEviction bool          // full queue evicts lower priority waiters
+	priorityAging    time.Duration // waiters gain one priority level per interval, 0 disables
+
+	weigher func(ctx context.Context) int // permits requested by a call, see WithWeigher
+
+	closePolicy BulkheadClosePolicy // what Close does with queued calls
+
+	discipline    QueueDiscipline // how the queue is served under overload
+	codelTarget   time.Duration   // acceptable standing queue wait
+	codelInterval time.Duration   // window for measuring the minimum queue wait
+
+	deadlineQuantile float64 // execution time quantile a queued call's deadline must cover, 0 disables
+
+	mutex    sync.Mutex
+	active   int       // permits held by running executions
+	inFlight int       // running executions
+	waiters  list.List // queued *bulkheadWaiter, by priority then arrival order
+	closed   bool
+	drained  chan struct{} // closed once a closed bulkhead has no calls left
 
-	sem   chan struct{} // semaphore for concurrent executions
-	queue chan struct{} // queue for queued executions
+	congested   bool          // minimum queue wait exceeded the target last interval
+	minSojourn  time.Duration // minimum queue wait in the current interval
+	intervalEnd time.Time
 
-	onRejected OnBulkheadRejectedFunc // on bulkhead limit exceeded
+	queueWait *latencyHistogram // queue wait durations
+	execution *latencyHistogram // execution durations
 
-	once sync.Once // for lazy initialization
+	admitted atomic.Uint64 // calls that got a slot
+	queued   atomic.Uint64 // calls that entered the queue
+	rejected atomic.Uint64 // calls rejected by the bulkhead
+
+	onRejected       OnBulkheadRejectedFunc       // on bulkhead limit exceeded
+	onRejectedReason OnBulkheadRejectedReasonFunc // on bulkhead limit exceeded, with reason
+	recoverPanics    bool                         // convert panics in fn into *PanicError
 }
 
 // NewBulkhead creates a bulkhead policy
@@ -33,11 +203,74 @@ func NewBulkhead(maxParallel, maxQueue int) *Bulkhead {
 	}
 
 	return &Bulkhead{
-		maxParallel: maxParallel,
-		maxQueue:    maxQueue,
+		maxParallel:   maxParallel,
+		maxQueue:      maxQueue,
+		codelTarget:   defaultCoDelTarget,
+		codelInterval: defaultCoDelInterval,
+		queueWait:     newLatencyHistogram(10000),
+		execution:     newLatencyHistogram(10000),
 	}
 }
 
+// WithMaxQueueWait limits how long a call may wait in the queue
+// 排队超过该时间的调用将被拒绝，返回 ErrBulkheadQueueTimeout。
+func (b *Bulkhead) WithMaxQueueWait(d time.Duration) *Bulkhead {
+	b.maxQueueWait = d
+	return b
+}
+
+// WithPriorityEviction lets a call arriving at a full queue evict the
+// lowest priority waiter, if that waiter has a lower priority than the call.
+// 被驱逐的调用返回 ErrBulkheadEvicted。
+func (b *Bulkhead) WithPriorityEviction(enabled bool) *Bulkhead {
+	b.priorityEviction = enabled
+	return b
+}
+
+// WithPriorityAging protects low priority calls from starvation: a waiter
+// gains one priority level for every interval spent in the queue.
+func (b *Bulkhead) WithPriorityAging(interval time.Duration) *Bulkhead {
+	b.priorityAging = interval
+	return b
+}
+
+// WithWeigher configures how many permits a call acquires, overriding
+// WithPermits. Requests above maxParallel are capped at maxParallel.
+// 例如大报表生成占用多个许可，简单读取只占用一个。
+func (b *Bulkhead) WithWeigher(fn func(ctx context.Context) int) *Bulkhead {
+	b.weigher = fn
+	return b
+}
+
+// WithQueueDiscipline selects how the queue is served under overload
+func (b *Bulkhead) WithQueueDiscipline(d QueueDiscipline) *Bulkhead {
+	b.discipline = d
+	return b
+}
+
+// WithCoDel configures congestion detection for AdaptiveLIFOQueue and
+// CoDelQueue: the queue is congested when the minimum wait over an interval
+// exceeds target. 默认 target 5ms，interval 100ms。
+func (b *Bulkhead) WithCoDel(target, interval time.Duration) *Bulkhead {
+	b.codelTarget = target
+	b.codelInterval = interval
+	return b
+}
+
+// WithDeadlineAwareQueue rejects queued calls whose ctx deadline leaves less
+// time than the given quantile (0..1) of observed execution times, instead
+// of giving them a slot they cannot use. 例如 0.5 表示典型（中位数）执行时间。
+func (b *Bulkhead) WithDeadlineAwareQueue(quantile float64) *Bulkhead {
+	b.deadlineQuantile = quantile
+	return b
+}
+
+// WithClosePolicy configures what Close does with queued calls
+func (b *Bulkhead) WithClosePolicy(policy BulkheadClosePolicy) *Bulkhead {
+	b.closePolicy = policy
+	return b
+}
+
 // OnRejected sets the callback for bulkhead rejections
 // 当 Bulkhead 拒绝请求时的回调函数。
 func (b *Bulkhead) OnRejected(fn OnBulkheadRejectedFunc) *Bulkhead {
@@ -45,48 +278,348 @@ func (b *Bulkhead) OnRejected(fn OnBulkheadRejectedFunc) *Bulkhead {
 	return b
 }
 
-// recently viewed file: bulkhead/bulkhead.go
-// Execute executes the given function with bulkhead policy
-func (b *Bulkhead) Execute(ctx context.Context, fn Func) error {
-	b.init()
+// OnRejectedWithReason sets the callback for bulkhead rejections, reporting the reason
+func (b *Bulkhead) OnRejectedWithReason(fn OnBulkheadRejectedReasonFunc) *Bulkhead {
+	b.onRejectedReason = fn
+	return b
+}
 
-	// Try enter execution slot immediately
-	select {
-	case b.sem <- struct{}{}:
-		defer func() { <-b.sem }()
-		return fn(ctx)
+// SetLimits changes the concurrency and queue limits at runtime.
+// In-flight and queued calls are kept when limits shrink; the new limits
+// apply to later admissions. Growing maxParallel admits waiters at once.
+func (b *Bulkhead) SetLimits(maxParallel, maxQueue int) error {
+	if maxParallel <= 0 {
+		return errors.New("maxParallel must be > 0")
+	}
+	if maxQueue < 0 {
+		return errors.New("maxQueue must be >= 0")
+	}
 
-	default:
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.maxParallel = maxParallel
+	b.maxQueue = maxQueue
+	b.admitLocked()
+	return nil
+}
+
+// Limits returns the current concurrency and queue limits
+func (b *Bulkhead) Limits() (maxParallel, maxQueue int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.maxParallel, b.maxQueue
+}
+
+// BulkheadStats is a point-in-time snapshot of a Bulkhead
+type BulkheadStats struct {
+	InFlight  int // 执行中的调用数
+	Queued    int // 排队中的调用数
+	Available int // 空闲许可数
+
+	TotalAdmitted uint64 // 累计放行数
+	TotalQueued   uint64 // 累计排队数
+	TotalRejected uint64 // 累计拒绝数
+
+	QueueWait LatencySummary // 排队时间分布
+	Execution LatencySummary // 执行时间分布
+}
+
+// Stats returns a snapshot for metrics exporters and health checks
+func (b *Bulkhead) Stats() BulkheadStats {
+	b.mutex.Lock()
+	stats := BulkheadStats{
+		InFlight:  b.inFlight,
+		Queued:    b.waiters.Len(),
+		Available: b.maxParallel - b.active,
 	}
+	b.mutex.Unlock()
+
+	if stats.Available < 0 {
+		// limits shrank below the permits in use
+		stats.Available = 0
+	}
+
+	stats.TotalAdmitted = b.admitted.Load()
+	stats.TotalQueued = b.queued.Load()
+	stats.TotalRejected = b.rejected.Load()
+	stats.QueueWait = b.queueWait.Summary()
+	stats.Execution = b.execution.Summary()
+	return stats
+}
+
+// QueueWaitStats returns the distribution of time spent in the queue
+func (b *Bulkhead) QueueWaitStats() LatencySummary {
+	return b.queueWait.Summary()
+}
+
+// Close stops admitting calls, handles queued calls according to the close
+// policy, then waits until running calls finish or ctx is done. Calls
+// arriving after Close are rejected with ErrBulkheadClosed. Close may be
+// called more than once, for example to wait again after ctx expired.
+func (b *Bulkhead) Close(ctx context.Context) error {
+	b.mutex.Lock()
+	if !b.closed {
+		b.closed = true
+		b.drained = make(chan struct{})
+
+		if b.closePolicy == CloseRejectQueued {
+			for b.waiters.Len() > 0 {
+				w := b.waiters.Remove(b.waiters.Front()).(*bulkheadWaiter)
+				w.fail(b.rejectionLocked(w.ctx, RejectClosed))
+			}
+		}
+		b.checkDrainedLocked()
+	}
+	drained := b.drained
+	b.mutex.Unlock()
 
-	// Try enter queue
 	select {
-	case b.queue <- struct{}{}:
-		defer func() { <-b.queue }()
+	case <-drained:
+		return nil
 	case <-ctx.Done():
 		return ctx.Err()
+	}
+}
+
+// checkDrainedLocked signals Close once nothing is running or queued
+func (b *Bulkhead) checkDrainedLocked() {
+	if !b.closed || b.inFlight > 0 || b.waiters.Len() > 0 {
+		return
+	}
+	select {
+	case <-b.drained:
 	default:
+		close(b.drained)
+	}
+}
+
+// RecoverPanics converts panics in fn into a *PanicError, see Recover
+func (b *Bulkhead) RecoverPanics() *Bulkhead {
+	b.recoverPanics = true
+	return b
+}
+
+// Execute executes the given function with bulkhead policy
+func (b *Bulkhead) Execute(ctx context.Context, fn Func) error {
+	if b.recoverPanics {
+		fn = recoverFunc(fn)
+	}
+
+	permits, err := b.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer b.release(permits)
+
+	start := time.Now()
+	defer func() { b.execution.Record(time.Since(start)) }()
+
+	return fn(ctx)
+}
+
+// permitsFor returns the number of permits requested by a call
+func (b *Bulkhead) permitsFor(ctx context.Context) int {
+	n := PermitsFromContext(ctx)
+	if b.weigher != nil {
+		n = b.weigher(ctx)
+	}
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// clampPermitsLocked caps a request at maxParallel, so a heavy call can
+// always run, alone if necessary
+func (b *Bulkhead) clampPermitsLocked(n int) int {
+	if n > b.maxParallel {
+		return b.maxParallel
+	}
+	return n
+}
+
+// acquire takes execution permits, queueing behind earlier callers if
+// needed. It returns the number of permits granted.
+func (b *Bulkhead) acquire(ctx context.Context) (int, error) {
+	permits := b.permitsFor(ctx)
+
+	b.mutex.Lock()
+
+	if b.closed {
+		return 0, b.rejectLocked(ctx, RejectClosed)
+	}
+
+	// Enter execution slot immediately, unless others are already waiting
+	if n := b.clampPermitsLocked(permits); b.active+n <= b.maxParallel && b.waiters.Len() == 0 {
+		b.grantLocked(n)
+		b.mutex.Unlock()
+		return n, nil
+	}
+
+	if ctx.Err() != nil {
+		return 0, b.rejectLocked(ctx, RejectCancelled)
+	}
+
+	w := newBulkheadWaiter(ctx)
+	w.permits = permits
+
+	// a call that cannot finish in time should not take a queue slot
+	if expected := b.expectedExecutionLocked(); w.tooLate(w.enqueued, expected) {
+		return 0, b.rejectLocked(ctx, RejectDeadline)
+	}
+
+	// make room by shedding stale waiters first
+	b.shedLocked(w.enqueued)
+
+	if b.waiters.Len() >= b.maxQueue && !b.evictForLocked(w) {
 		// queue full
-		if b.onRejected != nil {
-			b.onRejected(ctx)
-		}
-		return ErrBulkheadRejected
+		return 0, b.rejectLocked(ctx, RejectQueueFull)
+	}
+
+	b.enqueueLocked(w)
+	b.queued.Add(1)
+	b.admitLocked()
+	b.mutex.Unlock()
+
+	var timeout <-chan time.Time
+	if b.maxQueueWait > 0 {
+		timer := time.NewTimer(b.maxQueueWait)
+		defer timer.Stop()
+		timeout = timer.C
 	}
 
 	// Wait for execution slot
+	start := time.Now()
+	defer func() { b.queueWait.Record(time.Since(start)) }()
+
 	select {
-	case b.sem <- struct{}{}:
-		defer func() { <-b.sem }()
+	case <-w.ready:
+		if w.err != nil {
+			b.reject(ctx, w.err.Reason)
+			return 0, w.err
+		}
+		return w.permits, nil
 	case <-ctx.Done():
-		return ctx.Err()
+		if b.dequeue(w) {
+			// slot handed over concurrently, give it back
+			b.release(w.permits)
+		}
+		if w.err != nil {
+			// failed concurrently, report the real reason
+			b.reject(ctx, w.err.Reason)
+			return 0, w.err
+		}
+		b.mutex.Lock()
+		return 0, b.rejectLocked(ctx, RejectCancelled)
+	case <-timeout:
+		if b.dequeue(w) {
+			// slot handed over concurrently, use it
+			return w.permits, nil
+		}
+		if w.err != nil {
+			b.reject(ctx, w.err.Reason)
+			return 0, w.err
+		}
+		b.mutex.Lock()
+		return 0, b.rejectLocked(ctx, RejectQueueTimeout)
 	}
+}
 
-	return fn(ctx)
+// dequeue removes an abandoned waiter. It returns true if the waiter was
+// handed a slot before it could be removed.
+func (b *Bulkhead) dequeue(w *bulkheadWaiter) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	select {
+	case <-w.ready:
+		return w.err == nil
+	default:
+		b.waiters.Remove(w.elem)
+		// the waiter may have been blocking those behind it
+		b.admitLocked()
+		b.checkDrainedLocked()
+		return false
+	}
+}
+
+// release returns permits and hands them to the next waiters
+func (b *Bulkhead) release(permits int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.active -= permits
+	b.inFlight--
+	b.admitLocked()
+	b.checkDrainedLocked()
+}
+
+// admitLocked hands free permits to waiters, highest priority first. A
+// waiter that does not fit blocks those behind it, so heavy calls are not
+// starved by a stream of light ones.
+func (b *Bulkhead) admitLocked() {
+	now := time.Now()
+	b.updateCongestionLocked(now)
+	b.shedLocked(now)
+	b.dropLateLocked(now)
+
+	for b.waiters.Len() > 0 {
+		e := b.nextLocked(now)
+		w := e.Value.(*bulkheadWaiter)
+
+		n := b.clampPermitsLocked(w.permits)
+		if b.active+n > b.maxParallel {
+			return
+		}
+
+		b.waiters.Remove(e)
+		b.observeSojournLocked(now, now.Sub(w.enqueued))
+		b.grantLocked(n)
+		w.permits = n
+		close(w.ready)
+	}
 }
 
-func (b *Bulkhead) init() {
-	b.once.Do(func() {
-		b.sem = make(chan struct{}, b.maxParallel)
-		b.queue = make(chan struct{}, b.maxQueue)
-	})
+// grantLocked accounts for an admitted call holding n permits
+func (b *Bulkhead) grantLocked(n int) {
+	b.active += n
+	b.inFlight++
+	b.admitted.Add(1)
+}
+
+// rejectionLocked builds the error for a rejection, capturing the
+// occupancy at rejection time
+func (b *Bulkhead) rejectionLocked(ctx context.Context, reason BulkheadRejectReason) *BulkheadRejectedError {
+	return &BulkheadRejectedError{
+		Reason:   reason,
+		InFlight: b.inFlight,
+		Queued:   b.waiters.Len(),
+		Err:      rejectReasonErr(ctx, reason),
+	}
+}
+
+// rejectLocked builds the rejection error, then unlocks the mutex and
+// reports the rejection
+func (b *Bulkhead) rejectLocked(ctx context.Context, reason BulkheadRejectReason) error {
+	err := b.rejectionLocked(ctx, reason)
+	b.mutex.Unlock()
+
+	b.reject(ctx, reason)
+	return err
+}
+
+// reject reports a rejection to stats and callbacks. A call cancelled by
+// its own ctx is not counted as rejected by the bulkhead.
+func (b *Bulkhead) reject(ctx context.Context, reason BulkheadRejectReason) {
+	if reason == RejectCancelled {
+		return
+	}
+	b.rejected.Add(1)
+	if b.onRejected != nil {
+		b.onRejected(ctx)
+	}
+	if b.onRejectedReason != nil {
+		b.onRejectedReason(ctx, reason)
+	}
 }
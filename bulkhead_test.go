@@ -39,7 +39,7 @@ func TestBulkhead_MaxParallel(t *testing.T) {
 	wg.Wait()
 
 	for _, err := range errs {
-		if err != nil && err != ErrBulkheadRejected {
+		if err != nil && !errors.Is(err, ErrBulkheadRejected) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 	}
@@ -110,3 +110,619 @@ func TestBulkhead_ContextCancel(t *testing.T) {
 
 	<-done // 等待占用 goroutine 完成
 }
+
+// 排队超过最大等待时间被拒绝，回调报告原因
+func TestBulkhead_MaxQueueWait(t *testing.T) {
+	var reason BulkheadRejectReason = -1
+	bh := NewBulkhead(1, 1).
+		WithMaxQueueWait(10 * time.Millisecond).
+		OnRejectedWithReason(func(ctx context.Context, r BulkheadRejectReason) {
+			reason = r
+		})
+
+	start := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_ = bh.Execute(context.Background(), func(ctx context.Context) error {
+			close(start)
+			<-release
+			return nil
+		})
+	}()
+	<-start
+
+	err := bh.Execute(context.Background(), func(ctx context.Context) error {
+		return nil
+	})
+	close(release)
+
+	if !errors.Is(err, ErrBulkheadQueueTimeout) || !errors.Is(err, ErrBulkheadRejected) {
+		t.Fatalf("expected ErrBulkheadQueueTimeout, got %v", err)
+	}
+	if reason != RejectQueueTimeout {
+		t.Fatalf("expected RejectQueueTimeout, got %s", reason)
+	}
+
+	stats := bh.QueueWaitStats()
+	if stats.Samples != 1 || stats.P50 < 10*time.Millisecond {
+		t.Fatalf("unexpected queue wait stats: %+v", stats)
+	}
+}
+
+// 等待 n 个调用进入队列
+func waitQueued(t *testing.T, bh *Bulkhead, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		bh.mutex.Lock()
+		queued := bh.waiters.Len()
+		bh.mutex.Unlock()
+		if queued == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d queued calls", n)
+}
+
+// 排队调用严格按到达顺序执行，取消的排队调用不影响顺序
+func TestBulkhead_FIFO(t *testing.T) {
+	bh := NewBulkhead(1, 10)
+
+	start := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_ = bh.Execute(context.Background(), func(ctx context.Context) error {
+			close(start)
+			<-release
+			return nil
+		})
+	}()
+	<-start
+
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+
+	cancelCtx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < 5; i++ {
+		ctx := context.Background()
+		if i == 2 {
+			ctx = cancelCtx
+		}
+		wg.Add(1)
+		go func(i int, ctx context.Context) {
+			defer wg.Done()
+			err := bh.Execute(ctx, func(ctx context.Context) error {
+				mu.Lock()
+				order = append(order, i)
+				mu.Unlock()
+				return nil
+			})
+			if i == 2 && !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got %v", err)
+			}
+		}(i, ctx)
+		waitQueued(t, bh, i+1)
+	}
+
+	cancel()
+	waitQueued(t, bh, 4)
+
+	// 新调用不能插队
+	go func() {
+		_ = bh.Execute(context.Background(), func(ctx context.Context) error {
+			mu.Lock()
+			order = append(order, 5)
+			mu.Unlock()
+			return nil
+		})
+	}()
+	waitQueued(t, bh, 5)
+
+	close(release)
+	wg.Wait()
+	waitQueued(t, bh, 0)
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []int{0, 1, 3, 4}
+	for i, v := range want {
+		if order[i] != v {
+			t.Fatalf("expected order %v, got %v", want, order)
+		}
+	}
+}
+
+// 占满执行槽，返回释放函数
+func occupyBulkhead(t *testing.T, bh *Bulkhead) func() {
+	t.Helper()
+	start := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = bh.Execute(context.Background(), func(ctx context.Context) error {
+			close(start)
+			<-release
+			return nil
+		})
+	}()
+	<-start
+	return func() {
+		close(release)
+		<-done
+	}
+}
+
+// 高优先级先执行；队列满时驱逐最低优先级
+func TestBulkhead_Priority(t *testing.T) {
+	bh := NewBulkhead(1, 2).WithPriorityEviction(true)
+	release := occupyBulkhead(t, bh)
+
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	errs := make([]chan error, 3)
+
+	run := func(i, priority int) {
+		errs[i] = make(chan error, 1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] <- bh.Execute(WithPriority(context.Background(), priority), func(ctx context.Context) error {
+				mu.Lock()
+				order = append(order, i)
+				mu.Unlock()
+				return nil
+			})
+		}()
+	}
+
+	run(0, 0)
+	waitQueued(t, bh, 1)
+	run(1, 1)
+	waitQueued(t, bh, 2)
+
+	// 队列已满，同等优先级被拒绝
+	err := bh.Execute(context.Background(), func(ctx context.Context) error { return nil })
+	if !errors.Is(err, ErrBulkheadRejected) || errors.Is(err, ErrBulkheadEvicted) {
+		t.Fatalf("expected ErrBulkheadRejected, got %v", err)
+	}
+
+	// 更高优先级驱逐 0 号
+	run(2, 5)
+	if err := <-errs[0]; !errors.Is(err, ErrBulkheadEvicted) {
+		t.Fatalf("expected ErrBulkheadEvicted, got %v", err)
+	}
+	waitQueued(t, bh, 2)
+
+	release()
+	wg.Wait()
+
+	if len(order) != 2 || order[0] != 2 || order[1] != 1 {
+		t.Fatalf("expected order [2 1], got %v", order)
+	}
+}
+
+// 老化：等待足够久的低优先级调用不会被饿死
+func TestBulkhead_PriorityAging(t *testing.T) {
+	bh := NewBulkhead(1, 2).WithPriorityAging(10 * time.Millisecond)
+	release := occupyBulkhead(t, bh)
+
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	run := func(i, priority int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = bh.Execute(WithPriority(context.Background(), priority), func(ctx context.Context) error {
+				mu.Lock()
+				order = append(order, i)
+				mu.Unlock()
+				return nil
+			})
+		}()
+	}
+
+	run(0, 0)
+	waitQueued(t, bh, 1)
+	time.Sleep(50 * time.Millisecond)
+	run(1, 2)
+	waitQueued(t, bh, 2)
+
+	release()
+	wg.Wait()
+
+	if order[0] != 0 {
+		t.Fatalf("expected aged call first, got %v", order)
+	}
+}
+
+// 运行时调整限制：扩容后立即放行排队调用，缩容不影响执行中的调用
+func TestBulkhead_SetLimits(t *testing.T) {
+	bh := NewBulkhead(1, 5)
+	release := occupyBulkhead(t, bh)
+
+	admitted := make(chan struct{}, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_ = bh.Execute(context.Background(), func(ctx context.Context) error {
+				admitted <- struct{}{}
+				return nil
+			})
+		}()
+	}
+	waitQueued(t, bh, 2)
+
+	if err := bh.SetLimits(3, 5); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-admitted:
+		case <-time.After(time.Second):
+			t.Fatalf("queued call not admitted after growing limits")
+		}
+	}
+
+	// 缩容：队列为 0 时新调用被拒绝，执行中的调用不受影响
+	if err := bh.SetLimits(1, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := bh.Execute(context.Background(), func(ctx context.Context) error { return nil })
+	if !errors.Is(err, ErrBulkheadRejected) {
+		t.Fatalf("expected ErrBulkheadRejected, got %v", err)
+	}
+
+	release()
+	if maxParallel, maxQueue := bh.Limits(); maxParallel != 1 || maxQueue != 0 {
+		t.Fatalf("unexpected limits %d/%d", maxParallel, maxQueue)
+	}
+	if err := bh.SetLimits(0, 0); err == nil {
+		t.Fatalf("expected error for invalid limits")
+	}
+}
+
+// 加权许可：重任务占用多个许可，且不会被后续轻任务饿死
+func TestBulkhead_WeightedPermits(t *testing.T) {
+	bh := NewBulkhead(4, 10)
+
+	// 两个轻任务占用 2 个许可
+	releaseLight := occupyBulkhead(t, bh)
+	releaseLight2 := occupyBulkhead(t, bh)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) Func {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = bh.Execute(WithPermits(context.Background(), 3), record("heavy"))
+	}()
+	waitQueued(t, bh, 1)
+
+	// 还有 2 个空闲许可，但轻任务不能越过排队中的重任务
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = bh.Execute(context.Background(), record("light"))
+	}()
+	waitQueued(t, bh, 2)
+
+	// 释放一个许可后重任务先执行
+	releaseLight()
+	wg.Wait()
+	releaseLight2()
+
+	if len(order) != 2 || order[0] != "heavy" {
+		t.Fatalf("expected heavy call first, got %v", order)
+	}
+}
+
+// 超过 maxParallel 的请求被限制为 maxParallel，独占执行
+func TestBulkhead_WeigherCapped(t *testing.T) {
+	bh := NewBulkhead(2, 0).WithWeigher(func(ctx context.Context) int { return 10 })
+
+	err := bh.Execute(context.Background(), func(ctx context.Context) error {
+		bh.mutex.Lock()
+		active := bh.active
+		bh.mutex.Unlock()
+		if active != 2 {
+			t.Errorf("expected 2 permits held, got %d", active)
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Stats 快照：执行中、排队、空闲许可与累计计数
+func TestBulkhead_Stats(t *testing.T) {
+	bh := NewBulkhead(2, 1)
+	release := occupyBulkhead(t, bh)
+	release2 := occupyBulkhead(t, bh)
+
+	done := make(chan struct{})
+	go func() {
+		_ = bh.Execute(context.Background(), func(ctx context.Context) error { return nil })
+		close(done)
+	}()
+	waitQueued(t, bh, 1)
+
+	_ = bh.Execute(context.Background(), func(ctx context.Context) error { return nil })
+
+	stats := bh.Stats()
+	if stats.InFlight != 2 || stats.Queued != 1 || stats.Available != 0 {
+		t.Fatalf("unexpected occupancy: %+v", stats)
+	}
+	if stats.TotalAdmitted != 2 || stats.TotalQueued != 1 || stats.TotalRejected != 1 {
+		t.Fatalf("unexpected totals: %+v", stats)
+	}
+
+	release()
+	release2()
+	<-done
+
+	stats = bh.Stats()
+	if stats.InFlight != 0 || stats.Available != 2 || stats.TotalAdmitted != 3 {
+		t.Fatalf("unexpected stats after release: %+v", stats)
+	}
+	if stats.QueueWait.Samples != 1 || stats.Execution.Samples == 0 {
+		t.Fatalf("expected histogram samples: %+v", stats)
+	}
+}
+
+// Close：拒绝新调用和排队调用，等待执行中的调用结束
+func TestBulkhead_Close(t *testing.T) {
+	bh := NewBulkhead(1, 5)
+	release := occupyBulkhead(t, bh)
+
+	queued := make(chan error, 1)
+	go func() {
+		queued <- bh.Execute(context.Background(), func(ctx context.Context) error { return nil })
+	}()
+	waitQueued(t, bh, 1)
+
+	closed := make(chan error, 1)
+	go func() { closed <- bh.Close(context.Background()) }()
+
+	if err := <-queued; !errors.Is(err, ErrBulkheadClosed) || !errors.Is(err, ErrBulkheadRejected) {
+		t.Fatalf("expected ErrBulkheadClosed for queued call, got %v", err)
+	}
+
+	err := bh.Execute(context.Background(), func(ctx context.Context) error { return nil })
+	if !errors.Is(err, ErrBulkheadClosed) {
+		t.Fatalf("expected ErrBulkheadClosed, got %v", err)
+	}
+
+	select {
+	case <-closed:
+		t.Fatalf("Close returned before in-flight call finished")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	release()
+	if err := <-closed; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Close：CloseDrainQueued 放行排队调用；ctx 到期时返回
+func TestBulkhead_CloseDrainQueued(t *testing.T) {
+	bh := NewBulkhead(1, 5).WithClosePolicy(CloseDrainQueued)
+	release := occupyBulkhead(t, bh)
+
+	queued := make(chan error, 1)
+	go func() {
+		queued <- bh.Execute(context.Background(), func(ctx context.Context) error { return nil })
+	}()
+	waitQueued(t, bh, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bh.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	release()
+	if err := <-queued; err != nil {
+		t.Fatalf("expected queued call to run, got %v", err)
+	}
+	if err := bh.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// CoDel：排队过久的调用被丢弃
+func TestBulkhead_CoDel(t *testing.T) {
+	var reason BulkheadRejectReason = -1
+	bh := NewBulkhead(1, 1).
+		WithQueueDiscipline(CoDelQueue).
+		WithCoDel(time.Millisecond, 20*time.Millisecond).
+		OnRejectedWithReason(func(ctx context.Context, r BulkheadRejectReason) { reason = r })
+	release := occupyBulkhead(t, bh)
+
+	stale := make(chan error, 1)
+	go func() {
+		stale <- bh.Execute(context.Background(), func(ctx context.Context) error { return nil })
+	}()
+	waitQueued(t, bh, 1)
+	time.Sleep(30 * time.Millisecond)
+
+	// 队列已满，但过期的排队调用先被丢弃，新调用得以排队
+	fresh := make(chan error, 1)
+	go func() {
+		fresh <- bh.Execute(context.Background(), func(ctx context.Context) error { return nil })
+	}()
+
+	if err := <-stale; !errors.Is(err, ErrBulkheadShed) || !errors.Is(err, ErrBulkheadRejected) {
+		t.Fatalf("expected ErrBulkheadShed, got %v", err)
+	}
+	if reason != RejectShed {
+		t.Fatalf("expected RejectShed, got %s", reason)
+	}
+
+	waitQueued(t, bh, 1)
+	release()
+	if err := <-fresh; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// 自适应 LIFO：拥塞时优先服务最新的排队调用
+func TestBulkhead_AdaptiveLIFO(t *testing.T) {
+	bh := NewBulkhead(1, 5).
+		WithQueueDiscipline(AdaptiveLIFOQueue).
+		WithCoDel(time.Millisecond, 10*time.Millisecond)
+	release := occupyBulkhead(t, bh)
+
+	// A 排队 15ms 后执行并占用槽位，使本区间最小等待超过 target
+	startedA := make(chan struct{})
+	releaseA := make(chan struct{})
+	go func() {
+		_ = bh.Execute(context.Background(), func(ctx context.Context) error {
+			close(startedA)
+			<-releaseA
+			return nil
+		})
+	}()
+	waitQueued(t, bh, 1)
+	time.Sleep(15 * time.Millisecond)
+	release()
+	<-startedA
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	for i, name := range []string{"old", "new"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			_ = bh.Execute(context.Background(), func(ctx context.Context) error {
+				mu.Lock()
+				order = append(order, name)
+				mu.Unlock()
+				return nil
+			})
+		}(name)
+		waitQueued(t, bh, i+1)
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(15 * time.Millisecond)
+
+	close(releaseA)
+	wg.Wait()
+
+	if order[0] != "new" {
+		t.Fatalf("expected newest call first under congestion, got %v", order)
+	}
+}
+
+// 截止时间感知：剩余时间不足典型执行时间的排队调用被拒绝
+func TestBulkhead_DeadlineAwareQueue(t *testing.T) {
+	bh := NewBulkhead(1, 5).WithDeadlineAwareQueue(0.5)
+	for i := 0; i < minExecutionSamples; i++ {
+		bh.execution.Record(50 * time.Millisecond)
+	}
+	release := occupyBulkhead(t, bh)
+
+	// 入队前即可判断无法完成
+	short, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := bh.Execute(short, func(ctx context.Context) error { return nil })
+	if !errors.Is(err, ErrBulkheadDeadline) || !errors.Is(err, ErrBulkheadRejected) {
+		t.Fatalf("expected ErrBulkheadDeadline, got %v", err)
+	}
+
+	// 排队期间剩余时间变得不足
+	queuedCtx, cancel2 := context.WithTimeout(context.Background(), 80*time.Millisecond)
+	defer cancel2()
+	queued := make(chan error, 1)
+	go func() {
+		queued <- bh.Execute(queuedCtx, func(ctx context.Context) error { return nil })
+	}()
+	waitQueued(t, bh, 1)
+	time.Sleep(40 * time.Millisecond)
+
+	release()
+	if err := <-queued; !errors.Is(err, ErrBulkheadDeadline) {
+		t.Fatalf("expected ErrBulkheadDeadline, got %v", err)
+	}
+}
+
+// BulkheadRejectedError 包含拒绝原因和占用情况
+func TestBulkhead_RejectedError(t *testing.T) {
+	bh := NewBulkhead(1, 1)
+	release := occupyBulkhead(t, bh)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queued := make(chan error, 1)
+	go func() {
+		queued <- bh.Execute(ctx, func(ctx context.Context) error { return nil })
+	}()
+	waitQueued(t, bh, 1)
+
+	err := bh.Execute(context.Background(), func(ctx context.Context) error { return nil })
+	var re *BulkheadRejectedError
+	if !errors.As(err, &re) || !errors.Is(err, ErrBulkheadRejected) {
+		t.Fatalf("expected BulkheadRejectedError, got %v", err)
+	}
+	if re.Reason != RejectQueueFull || re.InFlight != 1 || re.Queued != 1 {
+		t.Fatalf("unexpected rejection: %+v", re)
+	}
+
+	cancel()
+	err = <-queued
+	if !errors.As(err, &re) || re.Reason != RejectCancelled || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancelled rejection, got %v", err)
+	}
+
+	release()
+	_ = bh.Close(context.Background())
+	err = bh.Execute(context.Background(), func(ctx context.Context) error { return nil })
+	if !errors.As(err, &re) || re.Reason != RejectClosed || !errors.Is(err, ErrBulkheadClosed) {
+		t.Fatalf("expected closed rejection, got %v", err)
+	}
+}
+
+// 等待者被驱逐的同时 ctx 结束，仍返回真实的拒绝原因
+func TestBulkhead_FailedWhileCancelled(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		bh := NewBulkhead(1, 1)
+		release := occupyBulkhead(t, bh)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- bh.Execute(ctx, func(ctx context.Context) error { return nil })
+		}()
+		waitQueued(t, bh, 1)
+
+		bh.mutex.Lock()
+		cancel()
+		e := bh.waiters.Front()
+		bh.waiters.Remove(e)
+		w := e.Value.(*bulkheadWaiter)
+		w.fail(bh.rejectionLocked(w.ctx, RejectEvicted))
+		bh.mutex.Unlock()
+
+		var rejected *BulkheadRejectedError
+		if err := <-done; !errors.As(err, &rejected) || rejected.Reason != RejectEvicted {
+			t.Fatalf("expected evicted rejection, got %v", err)
+		}
+		release()
+	}
+}
@@ -0,0 +1,308 @@
+package resilience
+
+import (
+	"container/list"
+	"context"
+	"time"
+)
+
+type priorityKey struct{}
+
+type permitsKey struct{}
+
+// WithPermits returns a ctx whose calls acquire n Bulkhead permits instead of one
+func WithPermits(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, permitsKey{}, n)
+}
+
+// PermitsFromContext returns the permits set by WithPermits, or 1
+func PermitsFromContext(ctx context.Context) int {
+	n, ok := ctx.Value(permitsKey{}).(int)
+	if !ok {
+		return 1
+	}
+	return n
+}
+
+// WithPriority returns a ctx whose calls are queued with the given priority.
+// Higher values are admitted first; the default priority is 0.
+// 优先级通过 ctx 传递，例如交互请求使用高优先级、批量导出使用低优先级。
+func WithPriority(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns the priority set by WithPriority, or 0
+func PriorityFromContext(ctx context.Context) int {
+	priority, _ := ctx.Value(priorityKey{}).(int)
+	return priority
+}
+
+// bulkheadWaiter is a call waiting in the queue
+type bulkheadWaiter struct {
+	priority int
+	permits  int // requested permits; set to the granted count on admission
+	enqueued time.Time
+	deadline time.Time // ctx deadline, zero if none
+	elem     *list.Element
+
+	ctx   context.Context
+	ready chan struct{}          // closed once a slot has been handed over, or on failure
+	err   *BulkheadRejectedError // set before ready is closed if the waiter failed
+}
+
+func newBulkheadWaiter(ctx context.Context) *bulkheadWaiter {
+	deadline, _ := ctx.Deadline()
+	return &bulkheadWaiter{
+		ctx:      ctx,
+		priority: PriorityFromContext(ctx),
+		permits:  1,
+		enqueued: time.Now(),
+		deadline: deadline,
+		ready:    make(chan struct{}),
+	}
+}
+
+// tooLate reports whether the waiter's deadline leaves less than expected
+func (w *bulkheadWaiter) tooLate(now time.Time, expected time.Duration) bool {
+	return expected > 0 && !w.deadline.IsZero() && w.deadline.Sub(now) < expected
+}
+
+// fail wakes the waiter with a rejection instead of a slot
+func (w *bulkheadWaiter) fail(err *BulkheadRejectedError) {
+	w.err = err
+	close(w.ready)
+}
+
+// effectivePriority adds the aging bonus to the waiter's priority
+func (b *Bulkhead) effectivePriority(w *bulkheadWaiter, now time.Time) int {
+	if b.priorityAging <= 0 {
+		return w.priority
+	}
+	return w.priority + int(now.Sub(w.enqueued)/b.priorityAging)
+}
+
+// enqueueLocked inserts w after every waiter with the same or higher priority
+func (b *Bulkhead) enqueueLocked(w *bulkheadWaiter) {
+	for e := b.waiters.Back(); e != nil; e = e.Prev() {
+		if e.Value.(*bulkheadWaiter).priority >= w.priority {
+			w.elem = b.waiters.InsertAfter(w, e)
+			return
+		}
+	}
+	w.elem = b.waiters.PushFront(w)
+}
+
+// nextLocked returns the waiter to admit next: the highest effective
+// priority, oldest among equals (newest when adaptive LIFO is active).
+func (b *Bulkhead) nextLocked(now time.Time) *list.Element {
+	lifo := b.discipline == AdaptiveLIFOQueue && b.congested
+
+	if b.priorityAging <= 0 {
+		// the list is already ordered by priority, then arrival
+		front := b.waiters.Front()
+		if !lifo {
+			return front
+		}
+		e := front
+		priority := front.Value.(*bulkheadWaiter).priority
+		for n := e.Next(); n != nil && n.Value.(*bulkheadWaiter).priority == priority; n = n.Next() {
+			e = n
+		}
+		return e
+	}
+
+	var best *list.Element
+	bestPriority := 0
+	for e := b.waiters.Front(); e != nil; e = e.Next() {
+		w := e.Value.(*bulkheadWaiter)
+		p := b.effectivePriority(w, now)
+		if best == nil || p > bestPriority {
+			best, bestPriority = e, p
+			continue
+		}
+		if p == bestPriority {
+			enqueued := best.Value.(*bulkheadWaiter).enqueued
+			if (!lifo && w.enqueued.Before(enqueued)) || (lifo && w.enqueued.After(enqueued)) {
+				best = e
+			}
+		}
+	}
+	return best
+}
+
+// victimLocked returns the waiter to evict: the lowest effective priority,
+// newest among equals.
+func (b *Bulkhead) victimLocked(now time.Time) *list.Element {
+	if b.priorityAging <= 0 {
+		return b.waiters.Back()
+	}
+
+	var victim *list.Element
+	victimPriority := 0
+	for e := b.waiters.Front(); e != nil; e = e.Next() {
+		w := e.Value.(*bulkheadWaiter)
+		p := b.effectivePriority(w, now)
+		if victim == nil || p < victimPriority ||
+			(p == victimPriority && w.enqueued.After(victim.Value.(*bulkheadWaiter).enqueued)) {
+			victim, victimPriority = e, p
+		}
+	}
+	return victim
+}
+
+// evictForLocked makes room for w in a full queue by evicting a lower
+// priority waiter. It returns false if nothing could be evicted.
+func (b *Bulkhead) evictForLocked(w *bulkheadWaiter) bool {
+	if !b.priorityEviction || b.waiters.Len() == 0 {
+		return false
+	}
+
+	e := b.victimLocked(w.enqueued)
+	victim := e.Value.(*bulkheadWaiter)
+	if b.effectivePriority(victim, w.enqueued) >= w.priority {
+		return false
+	}
+
+	b.waiters.Remove(e)
+	victim.fail(b.rejectionLocked(victim.ctx, RejectEvicted))
+	return true
+}
+
+/*
+========================
+ Queue disciplines
+========================
+*/
+
+// QueueDiscipline selects how a Bulkhead serves its queue under overload
+type QueueDiscipline int
+
+const (
+	// FIFOQueue serves queued calls in arrival order (default)
+	FIFOQueue QueueDiscipline = iota
+
+	// AdaptiveLIFOQueue serves in arrival order, but switches to newest
+	// first while the queue is congested: the callers of old requests have
+	// likely given up already.
+	AdaptiveLIFOQueue
+
+	// CoDelQueue sheds queued calls whose sojourn time exceeds the CoDel
+	// target while the queue is congested, or the interval otherwise.
+	CoDelQueue
+)
+
+func (d QueueDiscipline) String() string {
+	switch d {
+	case FIFOQueue:
+		return "FIFO"
+	case AdaptiveLIFOQueue:
+		return "AdaptiveLIFO"
+	case CoDelQueue:
+		return "CoDel"
+	default:
+		return "Unknown"
+	}
+}
+
+const (
+	defaultCoDelTarget   = 5 * time.Millisecond
+	defaultCoDelInterval = 100 * time.Millisecond
+)
+
+// noSojourn marks an interval without any dequeued waiter
+const noSojourn = time.Duration(1<<63 - 1)
+
+// updateCongestionLocked rolls the measurement interval over. The queue is
+// congested for the next interval if even the shortest wait in the last one
+// exceeded the target, or if nothing left a non-empty queue at all.
+func (b *Bulkhead) updateCongestionLocked(now time.Time) {
+	if b.discipline == FIFOQueue || !now.After(b.intervalEnd) {
+		return
+	}
+
+	switch {
+	case b.intervalEnd.IsZero():
+		b.congested = false
+	case b.minSojourn == noSojourn:
+		b.congested = b.waiters.Len() > 0
+	default:
+		b.congested = b.minSojourn > b.codelTarget
+	}
+
+	b.minSojourn = noSojourn
+	b.intervalEnd = now.Add(b.codelInterval)
+}
+
+// observeSojournLocked records the queue wait of a dequeued waiter
+func (b *Bulkhead) observeSojournLocked(now time.Time, sojourn time.Duration) {
+	if b.discipline == FIFOQueue {
+		return
+	}
+	b.updateCongestionLocked(now)
+	if sojourn < b.minSojourn {
+		b.minSojourn = sojourn
+	}
+}
+
+// shouldShedLocked reports whether CoDel drops a waiter with the given sojourn
+func (b *Bulkhead) shouldShedLocked(sojourn time.Duration) bool {
+	if b.discipline != CoDelQueue {
+		return false
+	}
+	if b.congested {
+		return sojourn > b.codelTarget
+	}
+	return sojourn > b.codelInterval
+}
+
+// shedLocked drops every waiter CoDel would not serve anymore
+func (b *Bulkhead) shedLocked(now time.Time) {
+	if b.discipline != CoDelQueue {
+		return
+	}
+	for e := b.waiters.Front(); e != nil; {
+		next := e.Next()
+		w := e.Value.(*bulkheadWaiter)
+		if sojourn := now.Sub(w.enqueued); b.shouldShedLocked(sojourn) {
+			b.observeSojournLocked(now, sojourn)
+			b.waiters.Remove(e)
+			w.fail(b.rejectionLocked(w.ctx, RejectShed))
+		}
+		e = next
+	}
+}
+
+/*
+========================
+ Deadline-aware dequeuing
+========================
+*/
+
+// minExecutionSamples is the number of executions observed before the
+// typical execution time is trusted
+const minExecutionSamples = 10
+
+// expectedExecutionLocked returns the typical execution time, or 0 if
+// deadline-aware dequeuing is disabled or has too few samples
+func (b *Bulkhead) expectedExecutionLocked() time.Duration {
+	if b.deadlineQuantile <= 0 || b.execution.Count() < minExecutionSamples {
+		return 0
+	}
+	return b.execution.Quantile(b.deadlineQuantile)
+}
+
+// dropLateLocked rejects every waiter that can no longer meet its deadline
+func (b *Bulkhead) dropLateLocked(now time.Time) {
+	expected := b.expectedExecutionLocked()
+	if expected <= 0 {
+		return
+	}
+	for e := b.waiters.Front(); e != nil; {
+		next := e.Next()
+		if w := e.Value.(*bulkheadWaiter); w.tooLate(now, expected) {
+			b.waiters.Remove(e)
+			w.fail(b.rejectionLocked(w.ctx, RejectDeadline))
+		}
+		e = next
+	}
+}
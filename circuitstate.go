@@ -46,9 +46,13 @@ type CircuitBreaker struct {
 
 	lastFailureTime time.Time
 
+	isFailure func(error) bool
+
 	onBreak    OnBreakFunc
 	onReset    OnResetFunc
 	onHalfOpen OnHalfOpenFunc
+
+	recoverPanics bool
 }
 
 // NewCircuitBreaker creates a circuit breaker policy
@@ -60,9 +64,17 @@ func NewCircuitBreaker(
 		failureThreshold: failureThreshold,
 		breakDuration:    breakDuration,
 		state:            Closed,
+		isFailure:        defaultHandle,
 	}
 }
 
+// Handle configures which errors count as failures.
+// 默认所有非 Permanent 的错误都计为失败。
+func (c *CircuitBreaker) Handle(f func(error) bool) *CircuitBreaker {
+	c.isFailure = f
+	return c
+}
+
 func (c *CircuitBreaker) OnBreak(f OnBreakFunc) *CircuitBreaker {
 	c.onBreak = f
 	return c
@@ -78,7 +90,17 @@ func (c *CircuitBreaker) OnHalfOpen(f OnHalfOpenFunc) *CircuitBreaker {
 	return c
 }
 
+// RecoverPanics converts panics in fn into a *PanicError, see Recover
+func (c *CircuitBreaker) RecoverPanics() *CircuitBreaker {
+	c.recoverPanics = true
+	return c
+}
+
 func (c *CircuitBreaker) Execute(ctx context.Context, fn Func) error {
+	if c.recoverPanics {
+		fn = recoverFunc(fn)
+	}
+
 	// pre-check
 	if err := c.beforeExecution(); err != nil {
 		return err
@@ -119,7 +141,7 @@ func (c *CircuitBreaker) afterExecution(err error) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	if err == nil {
+	if err == nil || !c.isFailure(err) {
 		if c.state == HalfOpen {
 			c.reset()
 		}
@@ -0,0 +1,210 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrBudgetExhausted is returned when the remaining deadline budget is too
+// small to start the execution. The default Retry predicate does not retry
+// it, since the budget only shrinks, while Fallback and friends still
+// handle it and may serve a substitute.
+var ErrBudgetExhausted = errors.New("deadline budget exhausted")
+
+// Header names used to propagate deadlines across service hops
+const (
+	HeaderGRPCTimeout     = "Grpc-Timeout"       // 相对超时，例如 "250m"
+	HeaderRequestDeadline = "X-Request-Deadline" // 绝对截止时间，Unix 毫秒
+)
+
+type OnBudgetExhaustedFunc func(
+	remaining time.Duration, // 扣除安全余量后的剩余预算
+
+	ctx context.Context, // 上下文环境
+)
+
+// DeadlineBudget derives the effective deadline from the caller's ctx minus
+// a safety margin, and rejects calls whose remaining budget is too small.
+type DeadlineBudget struct {
+	safetyMargin time.Duration // 为网络开销预留的时间
+	minBudget    time.Duration // 低于该预算时直接拒绝
+
+	onExhausted OnBudgetExhaustedFunc
+
+	recoverPanics bool
+}
+
+// NewDeadlineBudget creates a deadline budget policy
+func NewDeadlineBudget(safetyMargin time.Duration) *DeadlineBudget {
+	if safetyMargin < 0 {
+		panic("safetyMargin must be >= 0")
+	}
+	return &DeadlineBudget{
+		safetyMargin: safetyMargin,
+	}
+}
+
+// WithMinBudget rejects calls whose remaining budget is below min
+func (d *DeadlineBudget) WithMinBudget(min time.Duration) *DeadlineBudget {
+	d.minBudget = min
+	return d
+}
+
+// OnExhausted sets the callback for calls rejected for lack of budget
+func (d *DeadlineBudget) OnExhausted(fn OnBudgetExhaustedFunc) *DeadlineBudget {
+	d.onExhausted = fn
+	return d
+}
+
+// RecoverPanics converts panics in fn into a *PanicError, see Recover
+func (d *DeadlineBudget) RecoverPanics() *DeadlineBudget {
+	d.recoverPanics = true
+	return d
+}
+
+// Execute runs fn with the deadline shortened by the safety margin.
+// Without a caller deadline fn runs unchanged.
+func (d *DeadlineBudget) Execute(ctx context.Context, fn Func) error {
+	if d.recoverPanics {
+		fn = recoverFunc(fn)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return fn(ctx)
+	}
+
+	effective := deadline.Add(-d.safetyMargin)
+	remaining := time.Until(effective)
+
+	if remaining <= 0 || remaining < d.minBudget {
+		if d.onExhausted != nil {
+			d.onExhausted(remaining, ctx)
+		}
+		return ErrBudgetExhausted
+	}
+
+	ctx, cancel := context.WithDeadline(ctx, effective)
+	defer cancel()
+
+	return fn(ctx)
+}
+
+// RemainingBudget returns the time left until the ctx deadline.
+// ok is false when ctx has no deadline.
+func RemainingBudget(ctx context.Context) (remaining time.Duration, ok bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(deadline), true
+}
+
+/*
+========================
+ Header propagation
+========================
+*/
+
+// InjectDeadline writes the ctx deadline into both propagation headers.
+// Nothing is written when ctx has no deadline.
+func InjectDeadline(ctx context.Context, header http.Header) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+
+	remaining := time.Until(deadline)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	header.Set(HeaderGRPCTimeout, EncodeGRPCTimeout(remaining))
+	header.Set(HeaderRequestDeadline, strconv.FormatInt(deadline.UnixMilli(), 10))
+}
+
+// ExtractDeadline returns a ctx carrying the deadline found in header.
+// grpc-timeout is preferred over X-Request-Deadline; the earlier of the
+// header deadline and any existing ctx deadline wins.
+func ExtractDeadline(ctx context.Context, header http.Header) (context.Context, context.CancelFunc, error) {
+	if v := header.Get(HeaderGRPCTimeout); v != "" {
+		timeout, err := ParseGRPCTimeout(v)
+		if err != nil {
+			return ctx, func() {}, err
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		return ctx, cancel, nil
+	}
+
+	if v := header.Get(HeaderRequestDeadline); v != "" {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return ctx, func() {}, fmt.Errorf("invalid %s header %q: %w", HeaderRequestDeadline, v, err)
+		}
+		ctx, cancel := context.WithDeadline(ctx, time.UnixMilli(ms))
+		return ctx, cancel, nil
+	}
+
+	return ctx, func() {}, nil
+}
+
+// grpc-timeout 单位，按精度从粗到细排列
+var grpcTimeoutUnits = []struct {
+	unit byte
+	d    time.Duration
+}{
+	{'H', time.Hour},
+	{'M', time.Minute},
+	{'S', time.Second},
+	{'m', time.Millisecond},
+	{'u', time.Microsecond},
+	{'n', time.Nanosecond},
+}
+
+// grpc-timeout 最多 8 位数字
+const grpcTimeoutMaxValue = 99999999
+
+// EncodeGRPCTimeout encodes d in the grpc-timeout wire format, using the
+// finest unit whose value fits in 8 digits.
+func EncodeGRPCTimeout(d time.Duration) string {
+	if d <= 0 {
+		return "0n"
+	}
+	for i := len(grpcTimeoutUnits) - 1; i >= 0; i-- {
+		u := grpcTimeoutUnits[i]
+		// 向上取整，避免编码后的超时比实际更短
+		v := (d + u.d - 1) / u.d
+		if v <= grpcTimeoutMaxValue {
+			return strconv.FormatInt(int64(v), 10) + string(u.unit)
+		}
+	}
+	return strconv.Itoa(grpcTimeoutMaxValue) + "H"
+}
+
+// ParseGRPCTimeout parses a grpc-timeout header value
+func ParseGRPCTimeout(s string) (time.Duration, error) {
+	if len(s) < 2 || len(s) > 9 {
+		return 0, fmt.Errorf("invalid grpc-timeout %q", s)
+	}
+
+	v, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid grpc-timeout %q", s)
+	}
+
+	unit := s[len(s)-1]
+	for _, u := range grpcTimeoutUnits {
+		if u.unit == unit {
+			// 防止溢出
+			if u.d > time.Nanosecond && v > int64(1<<63-1)/int64(u.d) {
+				return 1<<63 - 1, nil
+			}
+			return time.Duration(v) * u.d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid grpc-timeout unit %q", s)
+}
@@ -0,0 +1,149 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// 扣除安全余量后执行，fn 可以读取剩余预算
+func TestDeadlineBudget_SafetyMargin(t *testing.T) {
+	db := NewDeadlineBudget(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := db.Execute(ctx, func(ctx context.Context) error {
+		remaining, ok := RemainingBudget(ctx)
+		if !ok {
+			return errors.New("expected deadline")
+		}
+		if remaining > 80*time.Millisecond || remaining < 50*time.Millisecond {
+			t.Errorf("unexpected remaining budget: %v", remaining)
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// 预算不足时立即拒绝
+func TestDeadlineBudget_Exhausted(t *testing.T) {
+	var exhausted bool
+	db := NewDeadlineBudget(5 * time.Millisecond).
+		WithMinBudget(50 * time.Millisecond).
+		OnExhausted(func(remaining time.Duration, ctx context.Context) {
+			exhausted = true
+		})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	called := false
+	err := db.Execute(ctx, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+
+	if !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("expected ErrBudgetExhausted, got %v", err)
+	}
+	if called || !exhausted {
+		t.Fatalf("fn should not run and OnExhausted should be called")
+	}
+}
+
+// 预算耗尽时 fallback 仍然生效，默认的重试不再重试
+func TestDeadlineBudget_ExhaustedWithFallbackAndRetry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	budget := NewDeadlineBudget(20 * time.Millisecond)
+	retry := NewRetry(3).OnRetry(func(attempt int, err error, delay time.Duration, ctx context.Context) {
+		attempts++
+	})
+
+	err := Wrap(retry, budget).Execute(ctx, func(ctx context.Context) error { return nil })
+	if !errors.Is(err, ErrBudgetExhausted) || attempts != 0 {
+		t.Fatalf("expected ErrBudgetExhausted without retries, got %v after %d retries", err, attempts)
+	}
+
+	fallback := NewFallback(func(ctx context.Context) error { return nil })
+	if err := Wrap(fallback, budget).Execute(ctx, func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatalf("expected fallback to handle exhausted budget, got %v", err)
+	}
+}
+
+// 没有截止时间时直接执行
+func TestDeadlineBudget_NoDeadline(t *testing.T) {
+	db := NewDeadlineBudget(time.Second).WithMinBudget(time.Second)
+
+	err := db.Execute(context.Background(), func(ctx context.Context) error {
+		if _, ok := RemainingBudget(ctx); ok {
+			return errors.New("unexpected deadline")
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// grpc-timeout 编解码
+func TestGRPCTimeout_EncodeParse(t *testing.T) {
+	cases := map[time.Duration]string{
+		0:                      "0n",
+		500 * time.Nanosecond:  "500n",
+		250 * time.Millisecond: "250000u",
+		3 * time.Minute:        "180000m",
+		1000 * time.Hour:       "3600000S",
+	}
+	for d, want := range cases {
+		if got := EncodeGRPCTimeout(d); got != want {
+			t.Fatalf("EncodeGRPCTimeout(%v) = %q, want %q", d, got, want)
+		}
+		if got, err := ParseGRPCTimeout(want); err != nil || got != d {
+			t.Fatalf("ParseGRPCTimeout(%q) = %v, %v", want, got, err)
+		}
+	}
+
+	for _, bad := range []string{"", "5", "123456789S", "10x", "-1S"} {
+		if _, err := ParseGRPCTimeout(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+// 截止时间通过 HTTP 头跨服务传递
+func TestDeadline_HeaderPropagation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	header := http.Header{}
+	InjectDeadline(ctx, header)
+
+	if header.Get(HeaderGRPCTimeout) == "" || header.Get(HeaderRequestDeadline) == "" {
+		t.Fatalf("expected both headers, got %v", header)
+	}
+
+	for _, name := range []string{HeaderGRPCTimeout, HeaderRequestDeadline} {
+		h := http.Header{}
+		h.Set(name, header.Get(name))
+
+		got, cancel, err := ExtractDeadline(context.Background(), h)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		remaining, ok := RemainingBudget(got)
+		cancel()
+		if !ok || remaining > 200*time.Millisecond || remaining < 150*time.Millisecond {
+			t.Fatalf("%s: unexpected remaining budget %v", name, remaining)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package resilience
+
+import "errors"
+
+// permanentError marks an error that will not go away by trying again.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// transientError marks an error that is expected to go away by trying again.
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// Permanent marks err as permanent: Retry stops immediately regardless of
+// its Handle predicate, and the default predicates of Fallback and
+// CircuitBreaker do not handle it.
+// 永久错误，重试无意义。errors.Is / errors.As 对原始错误保持透明。
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Transient marks err as transient: Retry retries it even if its Handle
+// predicate would not.
+// 临时错误，可以重试。errors.Is / errors.As 对原始错误保持透明。
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &transientError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// IsTransient reports whether err was marked with Transient.
+func IsTransient(err error) bool {
+	var t *transientError
+	return errors.As(err, &t)
+}
+
+// defaultHandle is the default error predicate shared by the policies
+func defaultHandle(err error) bool {
+	return err != nil && !IsPermanent(err)
+}
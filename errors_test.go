@@ -0,0 +1,104 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 标记对 errors.Is / errors.As 透明
+func TestErrorMarkers_Transparent(t *testing.T) {
+	base := errors.New("base")
+
+	p := Permanent(fmt.Errorf("wrapped: %w", base))
+	if !errors.Is(p, base) {
+		t.Fatalf("expected errors.Is to see through Permanent")
+	}
+	if !IsPermanent(p) || IsTransient(p) {
+		t.Fatalf("expected permanent only")
+	}
+
+	tr := Transient(&fs.PathError{Op: "open", Path: "x", Err: base})
+	var pe *fs.PathError
+	if !errors.As(tr, &pe) {
+		t.Fatalf("expected errors.As to see through Transient")
+	}
+	if !IsTransient(fmt.Errorf("outer: %w", tr)) {
+		t.Fatalf("expected transient through wrapping")
+	}
+
+	if Permanent(nil) != nil || Transient(nil) != nil {
+		t.Fatalf("expected nil for nil error")
+	}
+}
+
+// Permanent 立即停止重试，无视 Handle
+func TestRetry_PermanentStops(t *testing.T) {
+	var calls int32
+	r := NewRetry(5).
+		Handle(func(err error) bool { return true }).
+		WithBackoff(fakeBackoff{})
+
+	err := r.Execute(context.Background(), func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return Permanent(errors.New("bad request"))
+	})
+
+	if !IsPermanent(err) {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 call, got %d", calls)
+	}
+}
+
+// Transient 即使 Handle 不匹配也会重试
+func TestRetry_TransientOverridesHandle(t *testing.T) {
+	var calls int32
+	r := NewRetry(2).
+		Handle(func(err error) bool { return false }).
+		WithBackoff(fakeBackoff{})
+
+	_ = r.Execute(context.Background(), func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return Transient(errors.New("busy"))
+	})
+
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+}
+
+// 默认 Fallback 不处理 Permanent
+func TestFallback_PermanentNotHandled(t *testing.T) {
+	called := int32(0)
+	f := NewFallback(func(ctx context.Context) error {
+		atomic.AddInt32(&called, 1)
+		return nil
+	})
+
+	err := f.Execute(context.Background(), func(ctx context.Context) error {
+		return Permanent(errors.New("bad request"))
+	})
+
+	if err == nil || called != 0 {
+		t.Fatalf("fallback should not handle permanent error")
+	}
+}
+
+// 默认熔断器不把 Permanent 计为失败
+func TestCircuitBreaker_PermanentNotCounted(t *testing.T) {
+	cb := NewCircuitBreaker(1, 50*time.Millisecond)
+
+	_ = cb.Execute(context.Background(), func(ctx context.Context) error {
+		return Permanent(errors.New("bad request"))
+	})
+
+	if cb.state != Closed {
+		t.Fatalf("expected Closed, got %s", cb.state)
+	}
+}
@@ -6,19 +6,57 @@ import (
 
 type Fallback struct {
 	shouldFallback func(error) bool
-	fallbackFunc   Func
-	onFallback     OnFallbackFunc
+	fallbackFunc   FallbackFunc
+	onFallback     OnFallbackErrorFunc
+
+	recoverPanics bool
 }
 
+// FallbackFunc is a fallback action that receives the handled error, so it
+// can behave differently for, e.g., a timeout and an open circuit.
+type FallbackFunc func(ctx context.Context, err error) error
+
+// FallbackFuncT is the typed form of FallbackFunc returning a substitute value
+type FallbackFuncT[T any] func(ctx context.Context, err error) (T, error)
+
 type OnFallbackFunc func(err error, ctx context.Context)
 
+// OnFallbackErrorFunc inspects the handled error and returns the error
+// passed on to the fallback action. 返回 nil 时保留原错误。
+type OnFallbackErrorFunc func(err error, ctx context.Context) error
+
 // NewFallback creates a fallback policy
 func NewFallback(fallback Func) *Fallback {
+	return NewFallbackFunc(func(ctx context.Context, _ error) error {
+		return fallback(ctx)
+	})
+}
+
+// NewFallbackFunc creates a fallback policy whose action receives the
+// handled error
+func NewFallbackFunc(fallback FallbackFunc) *Fallback {
 	return &Fallback{
-		fallbackFunc: fallback,
-		shouldFallback: func(err error) bool {
-			return err != nil
-		},
+		fallbackFunc:   fallback,
+		shouldFallback: defaultHandle,
+	}
+}
+
+// NewFallbackT creates a fallback policy returning a substitute value of
+// type T. The value reaches the caller through ExecuteT; with a plain
+// Execute only the error is returned.
+func NewFallbackT[T any](fallback FallbackFuncT[T]) *Fallback {
+	return NewFallbackFunc(TypedFallback(fallback))
+}
+
+// TypedFallback adapts a typed fallback action to a FallbackFunc whose
+// substitute value is returned by ExecuteT
+func TypedFallback[T any](fallback FallbackFuncT[T]) FallbackFunc {
+	return func(ctx context.Context, err error) error {
+		value, err := fallback(ctx, err)
+		if err == nil {
+			setResult(ctx, value)
+		}
+		return err
 	}
 }
 
@@ -28,11 +66,31 @@ func (f *Fallback) Handle(fn func(error) bool) *Fallback {
 }
 
 func (f *Fallback) OnFallback(fn OnFallbackFunc) *Fallback {
+	f.onFallback = func(err error, ctx context.Context) error {
+		fn(err, ctx)
+		return err
+	}
+	return f
+}
+
+// OnFallbackError sets a callback that may inspect or replace the error
+// passed to the fallback action
+func (f *Fallback) OnFallbackError(fn OnFallbackErrorFunc) *Fallback {
 	f.onFallback = fn
 	return f
 }
 
+// RecoverPanics converts panics in fn into a *PanicError, see Recover
+func (f *Fallback) RecoverPanics() *Fallback {
+	f.recoverPanics = true
+	return f
+}
+
 func (f *Fallback) Execute(ctx context.Context, fn Func) error {
+	if f.recoverPanics {
+		fn = recoverFunc(fn)
+	}
+
 	err := fn(ctx)
 	if err == nil {
 		return nil
@@ -43,8 +101,10 @@ func (f *Fallback) Execute(ctx context.Context, fn Func) error {
 	}
 
 	if f.onFallback != nil {
-		f.onFallback(err, ctx)
+		if replaced := f.onFallback(err, ctx); replaced != nil {
+			err = replaced
+		}
 	}
 
-	return f.fallbackFunc(ctx)
+	return f.fallbackFunc(ctx, err)
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // 正常返回，不触发 fallback
@@ -103,3 +104,101 @@ func TestFallback_OnFallbackCalled(t *testing.T) {
 		t.Fatalf("OnFallback should receive correct context")
 	}
 }
+
+// fallback 动作收到被处理的错误
+func TestFallback_ReceivesError(t *testing.T) {
+	var seen error
+	f := NewFallbackFunc(func(ctx context.Context, err error) error {
+		seen = err
+		if errors.Is(err, ErrTimeout) {
+			return nil
+		}
+		return err
+	})
+
+	testErr := errors.New("fail")
+	if err := f.Execute(context.Background(), func(ctx context.Context) error { return testErr }); err != testErr {
+		t.Fatalf("expected original error, got %v", err)
+	}
+	if seen != testErr {
+		t.Fatalf("fallback should receive original error, got %v", seen)
+	}
+
+	if err := f.Execute(context.Background(), func(ctx context.Context) error { return ErrTimeout }); err != nil {
+		t.Fatalf("expected timeout to be handled, got %v", err)
+	}
+}
+
+// OnFallbackError 替换传给 fallback 的错误
+func TestFallback_OnFallbackErrorReplaces(t *testing.T) {
+	replaced := errors.New("replaced")
+	var seen error
+
+	f := NewFallbackFunc(func(ctx context.Context, err error) error {
+		seen = err
+		return err
+	}).OnFallbackError(func(err error, ctx context.Context) error {
+		return replaced
+	})
+
+	err := f.Execute(context.Background(), func(ctx context.Context) error {
+		return errors.New("fail")
+	})
+	if err != replaced || seen != replaced {
+		t.Fatalf("expected replaced error, got %v / %v", err, seen)
+	}
+}
+
+// 类型化执行路径返回 fn 的值
+func TestExecuteT_Value(t *testing.T) {
+	v, err := ExecuteT(context.Background(), NewRetry(3), func(ctx context.Context) (int, error) {
+		return 42, nil
+	})
+	if err != nil || v != 42 {
+		t.Fatalf("expected 42, got %v, %v", v, err)
+	}
+}
+
+// 类型化 fallback 返回替代值
+func TestFallbackT_Substitute(t *testing.T) {
+	policy := Wrap(
+		NewFallbackT(func(ctx context.Context, err error) (string, error) {
+			if errors.Is(err, ErrTimeout) {
+				return "cached", nil
+			}
+			return "", err
+		}),
+		NewTimeout(10*time.Millisecond),
+	)
+
+	v, err := ExecuteT(context.Background(), policy, func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	if err != nil || v != "cached" {
+		t.Fatalf("expected substitute value, got %q, %v", v, err)
+	}
+
+	// fallback 也失败时返回零值
+	v, err = ExecuteT(context.Background(), policy, func(ctx context.Context) (string, error) {
+		return "partial", errors.New("fail")
+	})
+	if err == nil || v != "" {
+		t.Fatalf("expected zero value and error, got %q, %v", v, err)
+	}
+}
+
+// fallback 未提供同类型的值时返回 ErrNoResult
+func TestExecuteT_NoResult(t *testing.T) {
+	failing := func(ctx context.Context) (string, error) { return "", errors.New("fail") }
+
+	untyped := NewFallback(func(ctx context.Context) error { return nil })
+	if _, err := ExecuteT(context.Background(), untyped, failing); !errors.Is(err, ErrNoResult) {
+		t.Fatalf("expected ErrNoResult, got %v", err)
+	}
+
+	mismatched := NewFallbackT(func(ctx context.Context, err error) (int, error) { return 1, nil })
+	if _, err := ExecuteT(context.Background(), mismatched, failing); !errors.Is(err, ErrNoResult) {
+		t.Fatalf("expected ErrNoResult, got %v", err)
+	}
+}
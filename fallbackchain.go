@@ -0,0 +1,144 @@
+package resilience
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// FallbackPrimary is the name under which the primary function is recorded
+const FallbackPrimary = "primary"
+
+// OnFallbackServedFunc is called with the name of the alternative that
+// served the call, FallbackPrimary if no fallback was needed
+type OnFallbackServedFunc func(name string, ctx context.Context)
+
+// FallbackFailure is the error of a single step of a fallback chain
+type FallbackFailure struct {
+	Name string
+	Err  error
+}
+
+// FallbackChainError is returned when every alternative of a chain failed.
+// errors.Is / errors.As match any of the failures.
+type FallbackChainError struct {
+	Failures []FallbackFailure // 按尝试顺序排列，第一个为 primary
+}
+
+func (e *FallbackChainError) Error() string {
+	parts := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		parts[i] = fmt.Sprintf("%s: %v", f.Name, f.Err)
+	}
+	return "all fallbacks failed: " + strings.Join(parts, "; ")
+}
+
+func (e *FallbackChainError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+type fallbackAlternative struct {
+	name string
+	fn   FallbackFunc
+}
+
+// FallbackChain implements the Resilience interface. When the primary
+// function fails it tries the alternatives in order, e.g. regional replica,
+// then cache, then a static default, and stops at the first success.
+type FallbackChain struct {
+	alternatives   []fallbackAlternative
+	shouldFallback func(error) bool
+	onServed       OnFallbackServedFunc
+
+	recoverPanics bool
+
+	mutex  sync.Mutex
+	served map[string]uint64
+}
+
+// NewFallbackChain creates a fallback chain policy; add alternatives with Then
+func NewFallbackChain() *FallbackChain {
+	return &FallbackChain{
+		shouldFallback: defaultHandle,
+		served:         make(map[string]uint64),
+	}
+}
+
+// Then appends an alternative. Each alternative receives the error of the
+// previous step.
+func (c *FallbackChain) Then(name string, fn FallbackFunc) *FallbackChain {
+	c.alternatives = append(c.alternatives, fallbackAlternative{name: name, fn: fn})
+	return c
+}
+
+// Handle configures which errors of the primary function trigger the chain
+func (c *FallbackChain) Handle(fn func(error) bool) *FallbackChain {
+	c.shouldFallback = fn
+	return c
+}
+
+// OnServed sets the callback reporting which alternative served each call
+func (c *FallbackChain) OnServed(fn OnFallbackServedFunc) *FallbackChain {
+	c.onServed = fn
+	return c
+}
+
+// Served returns how many calls each alternative served, by name
+func (c *FallbackChain) Served() map[string]uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	served := make(map[string]uint64, len(c.served))
+	for name, n := range c.served {
+		served[name] = n
+	}
+	return served
+}
+
+// RecoverPanics converts panics in fn into a *PanicError, see Recover
+func (c *FallbackChain) RecoverPanics() *FallbackChain {
+	c.recoverPanics = true
+	return c
+}
+
+func (c *FallbackChain) Execute(ctx context.Context, fn Func) error {
+	if c.recoverPanics {
+		fn = recoverFunc(fn)
+	}
+
+	err := fn(ctx)
+	if err == nil {
+		c.serve(FallbackPrimary, ctx)
+		return nil
+	}
+
+	if !c.shouldFallback(err) {
+		return err
+	}
+
+	failures := []FallbackFailure{{Name: FallbackPrimary, Err: err}}
+	for _, alt := range c.alternatives {
+		if err = alt.fn(ctx, err); err == nil {
+			c.serve(alt.name, ctx)
+			return nil
+		}
+		failures = append(failures, FallbackFailure{Name: alt.name, Err: err})
+	}
+
+	return &FallbackChainError{Failures: failures}
+}
+
+func (c *FallbackChain) serve(name string, ctx context.Context) {
+	c.mutex.Lock()
+	c.served[name]++
+	c.mutex.Unlock()
+
+	if c.onServed != nil {
+		c.onServed(name, ctx)
+	}
+}
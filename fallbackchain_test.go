@@ -0,0 +1,116 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var (
+	errReplica = errors.New("replica down")
+	errCache   = errors.New("cache miss")
+)
+
+// 按顺序尝试备选，首个成功即停止并记录来源
+func TestFallbackChain_FirstSuccess(t *testing.T) {
+	var tried []string
+	var servedBy string
+
+	chain := NewFallbackChain().
+		Then("replica", func(ctx context.Context, err error) error {
+			tried = append(tried, "replica")
+			return errReplica
+		}).
+		Then("cache", func(ctx context.Context, err error) error {
+			tried = append(tried, "cache")
+			if err != errReplica {
+				t.Errorf("cache should receive previous error, got %v", err)
+			}
+			return nil
+		}).
+		Then("static", func(ctx context.Context, err error) error {
+			tried = append(tried, "static")
+			return nil
+		}).
+		OnServed(func(name string, ctx context.Context) {
+			servedBy = name
+		})
+
+	err := chain.Execute(context.Background(), func(ctx context.Context) error {
+		return errors.New("primary down")
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if strings.Join(tried, ",") != "replica,cache" {
+		t.Fatalf("unexpected order %v", tried)
+	}
+	if servedBy != "cache" || chain.Served()["cache"] != 1 {
+		t.Fatalf("expected cache to serve, got %q %v", servedBy, chain.Served())
+	}
+
+	_ = chain.Execute(context.Background(), func(ctx context.Context) error { return nil })
+	if servedBy != FallbackPrimary {
+		t.Fatalf("expected primary to serve, got %q", servedBy)
+	}
+}
+
+// 全部失败时返回包含每个错误的聚合错误
+func TestFallbackChain_AllFail(t *testing.T) {
+	primaryErr := errors.New("primary down")
+	chain := NewFallbackChain().
+		Then("replica", func(ctx context.Context, err error) error { return errReplica }).
+		Then("cache", func(ctx context.Context, err error) error { return errCache })
+
+	err := chain.Execute(context.Background(), func(ctx context.Context) error { return primaryErr })
+
+	var chainErr *FallbackChainError
+	if !errors.As(err, &chainErr) || len(chainErr.Failures) != 3 {
+		t.Fatalf("expected aggregate error, got %v", err)
+	}
+	if chainErr.Failures[1].Name != "replica" || chainErr.Failures[2].Err != errCache {
+		t.Fatalf("unexpected failures %+v", chainErr.Failures)
+	}
+	if !errors.Is(err, primaryErr) || !errors.Is(err, errReplica) || !errors.Is(err, errCache) {
+		t.Fatal("aggregate error should match every failure")
+	}
+	if !strings.Contains(err.Error(), "primary: primary down") {
+		t.Fatalf("unexpected message %q", err.Error())
+	}
+}
+
+// 不处理的错误直接返回
+func TestFallbackChain_Handle(t *testing.T) {
+	called := false
+	chain := NewFallbackChain().
+		Then("cache", func(ctx context.Context, err error) error {
+			called = true
+			return nil
+		})
+
+	err := chain.Execute(context.Background(), func(ctx context.Context) error {
+		return Permanent(errCache)
+	})
+	if !errors.Is(err, errCache) || called {
+		t.Fatalf("permanent error should skip the chain, got %v", err)
+	}
+}
+
+// 类型化备选通过 ExecuteT 返回值
+func TestFallbackChain_Typed(t *testing.T) {
+	chain := NewFallbackChain().
+		Then("cache", TypedFallback(func(ctx context.Context, err error) (int, error) {
+			return 0, errCache
+		})).
+		Then("static", TypedFallback(func(ctx context.Context, err error) (int, error) {
+			return 7, nil
+		}))
+
+	v, err := ExecuteT(context.Background(), chain, func(ctx context.Context) (int, error) {
+		return 0, errReplica
+	})
+	if err != nil || v != 7 {
+		t.Fatalf("expected 7, got %v, %v", v, err)
+	}
+}
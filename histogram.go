@@ -0,0 +1,126 @@
+package resilience
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	histogramMinBound = 50 * time.Microsecond // 第一个桶的上界
+	histogramGrowth   = 1.1                   // 相邻桶上界之比，误差约 10%
+	histogramBuckets  = 200                   // 覆盖 50µs ~ 约 2.4 小时
+)
+
+var histogramBounds = func() [histogramBuckets]time.Duration {
+	var bounds [histogramBuckets]time.Duration
+	bound := float64(histogramMinBound)
+	for i := range bounds {
+		bounds[i] = time.Duration(bound)
+		bound *= histogramGrowth
+	}
+	return bounds
+}()
+
+// LatencySummary is a point-in-time view of a latency histogram
+type LatencySummary struct {
+	Samples uint64 // 当前保留的样本数（旧样本会衰减）
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+}
+
+// latencyHistogram is a streaming histogram with log-spaced buckets.
+// When the sample count reaches window all buckets are halved, so old
+// samples decay and the quantiles follow the recent latency.
+type latencyHistogram struct {
+	mutex  sync.Mutex
+	counts [histogramBuckets]uint64
+	total  uint64
+	window uint64
+}
+
+func newLatencyHistogram(window int) *latencyHistogram {
+	return &latencyHistogram{window: uint64(window)}
+}
+
+func histogramIndex(d time.Duration) int {
+	if d <= histogramMinBound {
+		return 0
+	}
+	i := int(math.Ceil(math.Log(float64(d)/float64(histogramMinBound)) / math.Log(histogramGrowth)))
+	if i >= histogramBuckets {
+		return histogramBuckets - 1
+	}
+	// 浮点误差修正
+	if histogramBounds[i] < d && i+1 < histogramBuckets {
+		i++
+	}
+	return i
+}
+
+// Record adds a single sample
+func (h *latencyHistogram) Record(d time.Duration) {
+	i := histogramIndex(d)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.counts[i]++
+	h.total++
+
+	if h.window > 0 && h.total >= h.window {
+		h.total = 0
+		for j := range h.counts {
+			h.counts[j] /= 2
+			h.total += h.counts[j]
+		}
+	}
+}
+
+// Count returns the number of samples currently held
+func (h *latencyHistogram) Count() uint64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.total
+}
+
+// Quantile returns the upper bound of the bucket holding quantile q (0..1)
+func (h *latencyHistogram) Quantile(q float64) time.Duration {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.quantileLocked(q)
+}
+
+func (h *latencyHistogram) quantileLocked(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(q * float64(h.total)))
+	if rank == 0 {
+		rank = 1
+	}
+
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			return histogramBounds[i]
+		}
+	}
+	return histogramBounds[histogramBuckets-1]
+}
+
+// Summary returns the sample count and common quantiles
+func (h *latencyHistogram) Summary() LatencySummary {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return LatencySummary{
+		Samples: h.total,
+		P50:     h.quantileLocked(0.5),
+		P90:     h.quantileLocked(0.9),
+		P99:     h.quantileLocked(0.99),
+	}
+}
@@ -0,0 +1,195 @@
+package resilience
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type cacheKey struct{}
+
+// WithCacheKey returns a ctx whose results are cached under key by
+// LastKnownGood
+func WithCacheKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, cacheKey{}, key)
+}
+
+// CacheKeyFromContext returns the key set by WithCacheKey, or ""
+func CacheKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(cacheKey{}).(string)
+	return key
+}
+
+type stalenessKey struct{}
+
+// Staleness tells whether a call was served from the last-known-good cache
+type Staleness struct {
+	Stale bool          // 结果来自缓存
+	Age   time.Duration // 缓存结果的年龄
+}
+
+// WithStaleness returns a ctx in which LastKnownGood records whether the
+// result it returned was stale. Read the Staleness after the call returns.
+func WithStaleness(ctx context.Context) (context.Context, *Staleness) {
+	s := &Staleness{}
+	return context.WithValue(ctx, stalenessKey{}, s), s
+}
+
+// OnStaleFunc is called when a cached result is served
+type OnStaleFunc func(key string, age time.Duration, ctx context.Context)
+
+type lkgEntry[T any] struct {
+	key    string
+	value  T
+	stored time.Time
+}
+
+// LastKnownGood implements the Resilience interface. It remembers the most
+// recent successful result per key and, like Fallback, serves it when the
+// live call fails. Values are captured through ExecuteT, so it only caches
+// calls made on the typed execution path; calls without a key are not
+// cached.
+type LastKnownGood[T any] struct {
+	maxStale time.Duration // 超过该年龄的结果不再提供，0 表示不限制
+	capacity int           // 最多缓存的 key 数，按 LRU 淘汰
+
+	keyFunc  PartitionKeyFunc
+	fallback *Fallback
+	onStale  OnStaleFunc
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element // of *lkgEntry[T]
+	lru     list.List                // most recently used at the front
+}
+
+// NewLastKnownGood creates a last-known-good fallback policy. Calls are
+// keyed by CacheKeyFromContext unless WithKeyFunc is used.
+func NewLastKnownGood[T any](maxStale time.Duration, capacity int) *LastKnownGood[T] {
+	if capacity <= 0 {
+		panic("capacity must be > 0")
+	}
+
+	c := &LastKnownGood[T]{
+		maxStale: maxStale,
+		capacity: capacity,
+		keyFunc:  CacheKeyFromContext,
+		entries:  make(map[string]*list.Element),
+	}
+	c.fallback = NewFallbackFunc(c.serveStale)
+	return c
+}
+
+// WithKeyFunc configures how the cache key is extracted from ctx
+func (c *LastKnownGood[T]) WithKeyFunc(fn PartitionKeyFunc) *LastKnownGood[T] {
+	c.keyFunc = fn
+	return c
+}
+
+// Handle configures which errors are answered from the cache
+func (c *LastKnownGood[T]) Handle(fn func(error) bool) *LastKnownGood[T] {
+	c.fallback.Handle(fn)
+	return c
+}
+
+// RecoverPanics converts panics in fn into a *PanicError, see Recover
+func (c *LastKnownGood[T]) RecoverPanics() *LastKnownGood[T] {
+	c.fallback.RecoverPanics()
+	return c
+}
+
+// OnStale sets the callback for calls served from the cache
+func (c *LastKnownGood[T]) OnStale(fn OnStaleFunc) *LastKnownGood[T] {
+	c.onStale = fn
+	return c
+}
+
+// Len returns the number of cached keys
+func (c *LastKnownGood[T]) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.lru.Len()
+}
+
+func (c *LastKnownGood[T]) Execute(ctx context.Context, fn Func) error {
+	return c.fallback.Execute(ctx, func(ctx context.Context) error {
+		res := &result[T]{}
+		if err := fn(context.WithValue(ctx, resultKey[T]{}, res)); err != nil {
+			return err
+		}
+
+		value, ok := res.get()
+		if !ok {
+			return nil
+		}
+		if key := c.keyFunc(ctx); key != "" {
+			c.store(key, value)
+		}
+		setResult(ctx, value)
+		return nil
+	})
+}
+
+// serveStale is the fallback action: it answers from the cache or returns
+// the live error when there is no fresh enough entry
+func (c *LastKnownGood[T]) serveStale(ctx context.Context, err error) error {
+	key := c.keyFunc(ctx)
+	if key == "" {
+		return err
+	}
+
+	value, age, ok := c.load(key)
+	if !ok {
+		return err
+	}
+
+	setResult(ctx, value)
+	if s, ok := ctx.Value(stalenessKey{}).(*Staleness); ok {
+		s.Stale, s.Age = true, age
+	}
+	if c.onStale != nil {
+		c.onStale(key, age, ctx)
+	}
+	return nil
+}
+
+func (c *LastKnownGood[T]) store(key string, value T) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*lkgEntry[T])
+		entry.value, entry.stored = value, time.Now()
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&lkgEntry[T]{key: key, value: value, stored: time.Now()})
+	if c.lru.Len() > c.capacity {
+		oldest := c.lru.Remove(c.lru.Back()).(*lkgEntry[T])
+		delete(c.entries, oldest.key)
+	}
+}
+
+// load returns the entry for key unless it is older than maxStale.
+// Expired entries are removed.
+func (c *LastKnownGood[T]) load(key string) (value T, age time.Duration, ok bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return value, 0, false
+	}
+
+	entry := elem.Value.(*lkgEntry[T])
+	age = time.Since(entry.stored)
+	if c.maxStale > 0 && age > c.maxStale {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return value, 0, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return entry.value, age, true
+}
@@ -0,0 +1,107 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var errLive = errors.New("live call failed")
+
+func fetch(v string, err error) FuncT[string] {
+	return func(ctx context.Context) (string, error) {
+		return v, err
+	}
+}
+
+// 实时调用失败时返回最近一次成功的结果，并标记为陈旧
+func TestLastKnownGood_ServesStale(t *testing.T) {
+	lkg := NewLastKnownGood[string](time.Minute, 10)
+	ctx := WithCacheKey(context.Background(), "user:1")
+
+	v, err := ExecuteT(ctx, lkg, fetch("alice", nil))
+	if err != nil || v != "alice" {
+		t.Fatalf("expected live value, got %q, %v", v, err)
+	}
+
+	var staleKey string
+	lkg.OnStale(func(key string, age time.Duration, ctx context.Context) {
+		staleKey = key
+	})
+
+	sctx, staleness := WithStaleness(ctx)
+	v, err = ExecuteT(sctx, lkg, fetch("", errLive))
+	if err != nil || v != "alice" {
+		t.Fatalf("expected cached value, got %q, %v", v, err)
+	}
+	if !staleness.Stale || staleKey != "user:1" {
+		t.Fatalf("expected stale marker, got %+v %q", staleness, staleKey)
+	}
+
+	// 其它 key 没有缓存，返回原错误
+	_, err = ExecuteT(WithCacheKey(context.Background(), "user:2"), lkg, fetch("", errLive))
+	if err != errLive {
+		t.Fatalf("expected live error, got %v", err)
+	}
+}
+
+// 超过最大陈旧时间的结果不再提供
+func TestLastKnownGood_MaxStale(t *testing.T) {
+	lkg := NewLastKnownGood[string](20*time.Millisecond, 10)
+	ctx := WithCacheKey(context.Background(), "k")
+
+	_, _ = ExecuteT(ctx, lkg, fetch("v", nil))
+	time.Sleep(30 * time.Millisecond)
+
+	sctx, staleness := WithStaleness(ctx)
+	if _, err := ExecuteT(sctx, lkg, fetch("", errLive)); err != errLive {
+		t.Fatalf("expected live error, got %v", err)
+	}
+	if staleness.Stale || lkg.Len() != 0 {
+		t.Fatalf("expired entry should be dropped, %+v len %d", staleness, lkg.Len())
+	}
+}
+
+// LRU 淘汰最久未使用的 key
+func TestLastKnownGood_LRU(t *testing.T) {
+	lkg := NewLastKnownGood[int](time.Minute, 2)
+	key := func(i int) context.Context {
+		return WithCacheKey(context.Background(), strconv.Itoa(i))
+	}
+	value := func(v int, err error) FuncT[int] {
+		return func(ctx context.Context) (int, error) { return v, err }
+	}
+
+	_, _ = ExecuteT(key(1), lkg, value(1, nil))
+	_, _ = ExecuteT(key(2), lkg, value(2, nil))
+	// 访问 1，使 2 成为最久未使用
+	_, _ = ExecuteT(key(1), lkg, value(0, errLive))
+	_, _ = ExecuteT(key(3), lkg, value(3, nil))
+
+	if lkg.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", lkg.Len())
+	}
+	if _, err := ExecuteT(key(2), lkg, value(0, errLive)); err != errLive {
+		t.Fatalf("expected key 2 evicted, got %v", err)
+	}
+	if v, err := ExecuteT(key(1), lkg, value(0, errLive)); err != nil || v != 1 {
+		t.Fatalf("expected key 1 cached, got %v, %v", v, err)
+	}
+}
+
+// 与其它策略组合时同样生效，永久错误不走缓存
+func TestLastKnownGood_Wrap(t *testing.T) {
+	lkg := NewLastKnownGood[string](time.Minute, 10)
+	policy := Wrap(lkg, NewRetry(2))
+	ctx := WithCacheKey(context.Background(), "k")
+
+	_, _ = ExecuteT(ctx, policy, fetch("v", nil))
+	if v, err := ExecuteT(ctx, policy, fetch("", errLive)); err != nil || v != "v" {
+		t.Fatalf("expected cached value, got %q, %v", v, err)
+	}
+	if _, err := ExecuteT(ctx, policy, fetch("", Permanent(errLive))); !errors.Is(err, errLive) {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+}
@@ -0,0 +1,300 @@
+package resilience
+
+import (
+	"math"
+	"time"
+)
+
+// LimitSample is the outcome of a single execution seen by a LimitAlgorithm
+type LimitSample struct {
+	RTT      time.Duration // 执行耗时
+	InFlight int           // 开始执行时的并发数（含本次）
+	Dropped  bool          // 是否为过载信号（超时、被拒绝等）
+}
+
+// LimitAlgorithm computes a concurrency limit from execution samples.
+// AdaptiveLimiter calls it under its own lock, so implementations need not
+// be safe for concurrent use.
+type LimitAlgorithm interface {
+	// Limit returns the current limit
+	Limit() int
+
+	// Update feeds a sample and returns the new limit
+	Update(sample LimitSample) int
+}
+
+// clampLimit keeps limit within [min, max]; max <= 0 means unbounded
+func clampLimit(limit float64, min, max int) float64 {
+	if max > 0 && limit > float64(max) {
+		limit = float64(max)
+	}
+	if limit < float64(min) {
+		limit = float64(min)
+	}
+	return limit
+}
+
+/*
+========================
+ AIMD
+========================
+*/
+
+// AIMDLimitOptions configures an AIMDLimit
+type AIMDLimitOptions struct {
+	InitialLimit int           // 默认 20
+	MinLimit     int           // 默认 1
+	MaxLimit     int           // 默认 200
+	BackoffRatio float64       // 出现过载时的乘性缩减比例，默认 0.9
+	Timeout      time.Duration // 耗时超过该值也视为过载，0 表示不判断
+}
+
+// AIMDLimit increases the limit by one on success and multiplies it by
+// BackoffRatio on a drop (additive increase, multiplicative decrease).
+type AIMDLimit struct {
+	opts  AIMDLimitOptions
+	limit float64
+}
+
+// NewAIMDLimit creates an AIMD limit algorithm
+func NewAIMDLimit(opts AIMDLimitOptions) *AIMDLimit {
+	if opts.InitialLimit <= 0 {
+		opts.InitialLimit = 20
+	}
+	if opts.MinLimit <= 0 {
+		opts.MinLimit = 1
+	}
+	if opts.MaxLimit <= 0 {
+		opts.MaxLimit = 200
+	}
+	if opts.BackoffRatio <= 0 || opts.BackoffRatio >= 1 {
+		opts.BackoffRatio = 0.9
+	}
+
+	return &AIMDLimit{
+		opts:  opts,
+		limit: clampLimit(float64(opts.InitialLimit), opts.MinLimit, opts.MaxLimit),
+	}
+}
+
+func (a *AIMDLimit) Limit() int {
+	return int(a.limit)
+}
+
+func (a *AIMDLimit) Update(sample LimitSample) int {
+	dropped := sample.Dropped || (a.opts.Timeout > 0 && sample.RTT > a.opts.Timeout)
+
+	switch {
+	case dropped:
+		a.limit = math.Floor(a.limit * a.opts.BackoffRatio)
+	case sample.InFlight*2 >= int(a.limit):
+		// 仅在并发接近上限时增长，避免低负载下无限膨胀
+		a.limit++
+	}
+
+	a.limit = clampLimit(a.limit, a.opts.MinLimit, a.opts.MaxLimit)
+	return int(a.limit)
+}
+
+/*
+========================
+ Vegas
+========================
+*/
+
+// VegasLimitOptions configures a VegasLimit
+type VegasLimitOptions struct {
+	InitialLimit int // 默认 20
+	MinLimit     int // 默认 1
+	MaxLimit     int // 默认 1000
+
+	// ProbeMultiplier controls how often the no-load RTT is re-measured:
+	// every ProbeMultiplier × limit samples. 默认 30
+	ProbeMultiplier int
+}
+
+// VegasLimit estimates the queue built up in the dependency from the ratio
+// of the no-load RTT to the current RTT, in the spirit of TCP Vegas. The
+// limit grows while the estimated queue is small and shrinks when it grows.
+type VegasLimit struct {
+	opts  VegasLimitOptions
+	limit float64
+
+	rttNoLoad time.Duration // 观察到的最小耗时
+	samples   int           // 距上次探测的样本数
+}
+
+// NewVegasLimit creates a Vegas limit algorithm
+func NewVegasLimit(opts VegasLimitOptions) *VegasLimit {
+	if opts.InitialLimit <= 0 {
+		opts.InitialLimit = 20
+	}
+	if opts.MinLimit <= 0 {
+		opts.MinLimit = 1
+	}
+	if opts.MaxLimit <= 0 {
+		opts.MaxLimit = 1000
+	}
+	if opts.ProbeMultiplier <= 0 {
+		opts.ProbeMultiplier = 30
+	}
+
+	return &VegasLimit{
+		opts:  opts,
+		limit: clampLimit(float64(opts.InitialLimit), opts.MinLimit, opts.MaxLimit),
+	}
+}
+
+func (v *VegasLimit) Limit() int {
+	return int(v.limit)
+}
+
+func (v *VegasLimit) Update(sample LimitSample) int {
+	// 过载信号优先处理，快速失败的耗时不计入无负载耗时
+	if sample.Dropped {
+		v.limit = clampLimit(v.limit-math.Max(1, math.Log10(v.limit)), v.opts.MinLimit, v.opts.MaxLimit)
+		return int(v.limit)
+	}
+	if sample.RTT <= 0 {
+		return int(v.limit)
+	}
+
+	// 定期重新测量无负载耗时
+	v.samples++
+	if v.samples >= v.opts.ProbeMultiplier*int(v.limit) {
+		v.samples = 0
+		v.rttNoLoad = 0
+	}
+
+	if v.rttNoLoad == 0 || sample.RTT < v.rttNoLoad {
+		v.rttNoLoad = sample.RTT
+		return int(v.limit)
+	}
+
+	step := math.Max(1, math.Log10(v.limit))
+	alpha := 3 * step
+	beta := 6 * step
+
+	queueSize := math.Ceil(v.limit * (1 - float64(v.rttNoLoad)/float64(sample.RTT)))
+
+	switch {
+	case queueSize <= step:
+		// 几乎无排队，快速增长
+		if sample.InFlight*2 >= int(v.limit) {
+			v.limit += beta
+		}
+	case queueSize < alpha:
+		if sample.InFlight*2 >= int(v.limit) {
+			v.limit += step
+		}
+	case queueSize > beta:
+		v.limit -= step
+	}
+
+	v.limit = clampLimit(v.limit, v.opts.MinLimit, v.opts.MaxLimit)
+	return int(v.limit)
+}
+
+/*
+========================
+ Gradient2
+========================
+*/
+
+// Gradient2LimitOptions configures a Gradient2Limit
+type Gradient2LimitOptions struct {
+	InitialLimit int     // 默认 20
+	MinLimit     int     // 默认 1
+	MaxLimit     int     // 默认 200
+	Smoothing    float64 // 新旧 limit 的平滑系数，默认 0.2
+	Tolerance    float64 // 允许长期耗时被超过的倍数，默认 1.5
+	QueueSize    int     // limit 之外额外允许的排队数，默认 4
+	LongWindow   int     // 长期耗时指数平均的窗口，默认 600
+	BackoffRatio float64 // 出现过载时的乘性缩减比例，默认 0.9
+}
+
+// Gradient2Limit compares a long-term exponential average RTT with the
+// latest RTT. When the latest RTT exceeds the long-term one the gradient
+// drops below 1 and the limit shrinks proportionally. A drop multiplies the
+// limit by BackoffRatio and is left out of the RTT averages.
+type Gradient2Limit struct {
+	opts  Gradient2LimitOptions
+	limit float64
+
+	longRTT float64 // 长期耗时指数平均
+	samples int
+}
+
+// NewGradient2Limit creates a Gradient2 limit algorithm
+func NewGradient2Limit(opts Gradient2LimitOptions) *Gradient2Limit {
+	if opts.InitialLimit <= 0 {
+		opts.InitialLimit = 20
+	}
+	if opts.MinLimit <= 0 {
+		opts.MinLimit = 1
+	}
+	if opts.MaxLimit <= 0 {
+		opts.MaxLimit = 200
+	}
+	if opts.Smoothing <= 0 || opts.Smoothing > 1 {
+		opts.Smoothing = 0.2
+	}
+	if opts.Tolerance < 1 {
+		opts.Tolerance = 1.5
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 4
+	}
+	if opts.LongWindow <= 0 {
+		opts.LongWindow = 600
+	}
+	if opts.BackoffRatio <= 0 || opts.BackoffRatio >= 1 {
+		opts.BackoffRatio = 0.9
+	}
+
+	return &Gradient2Limit{
+		opts:  opts,
+		limit: clampLimit(float64(opts.InitialLimit), opts.MinLimit, opts.MaxLimit),
+	}
+}
+
+func (g *Gradient2Limit) Limit() int {
+	return int(g.limit)
+}
+
+func (g *Gradient2Limit) Update(sample LimitSample) int {
+	// 快速失败的过载信号不能当作低耗时的成功
+	if sample.Dropped {
+		g.limit = clampLimit(math.Floor(g.limit*g.opts.BackoffRatio), g.opts.MinLimit, g.opts.MaxLimit)
+		return int(g.limit)
+	}
+	if sample.RTT <= 0 {
+		return int(g.limit)
+	}
+	shortRTT := float64(sample.RTT)
+
+	// 预热阶段使用简单平均，之后使用指数平均
+	g.samples++
+	if g.samples <= 10 {
+		g.longRTT += (shortRTT - g.longRTT) / float64(g.samples)
+	} else {
+		g.longRTT += (shortRTT - g.longRTT) * 2 / float64(g.opts.LongWindow+1)
+	}
+
+	// 长期耗时远高于当前耗时，说明负载已恢复，加速衰减
+	if g.longRTT/shortRTT > 2 {
+		g.longRTT *= 0.95
+	}
+
+	// 低负载时不调整，避免 limit 在空闲时漂移
+	if sample.InFlight*2 < int(g.limit) {
+		return int(g.limit)
+	}
+
+	gradient := math.Max(0.5, math.Min(1, g.opts.Tolerance*g.longRTT/shortRTT))
+	newLimit := g.limit*gradient + float64(g.opts.QueueSize)
+	g.limit = g.limit*(1-g.opts.Smoothing) + newLimit*g.opts.Smoothing
+
+	g.limit = clampLimit(g.limit, g.opts.MinLimit, g.opts.MaxLimit)
+	return int(g.limit)
+}
@@ -0,0 +1,412 @@
+package resilience
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type partitionKey struct{}
+
+// WithPartitionKey returns a ctx whose calls are assigned to the given
+// partition (tenant, API key, client, ...).
+func WithPartitionKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, partitionKey{}, key)
+}
+
+// PartitionKeyFromContext returns the key set by WithPartitionKey, or ""
+func PartitionKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(partitionKey{}).(string)
+	return key
+}
+
+// PartitionKeyFunc extracts the partition key of a call
+type PartitionKeyFunc func(ctx context.Context) string
+
+// PartitionStats is a snapshot of a single partition
+type PartitionStats struct {
+	Active   int    // 执行中的调用数
+	Queued   int    // 排队中的调用数
+	Admitted uint64 // 累计放行数
+	Rejected uint64 // 累计拒绝数
+}
+
+// bulkheadPartition is the state of a single partition
+type bulkheadPartition struct {
+	active   int
+	waiters  list.List // queued *bulkheadWaiter, in arrival order
+	lastUsed time.Time
+
+	admitted uint64
+	rejected uint64
+}
+
+// PartitionedBulkhead implements the Resilience interface. Each partition
+// is guaranteed a minimum number of slots and may burst into the shared
+// remainder of a global maximum, so one noisy partition cannot starve the
+// others. Only partitions with running or queued calls hold a reservation,
+// so idle keys do not shrink the shared pool. Bursting partitions also leave
+// guaranteed slots free as headroom, so a newly arriving partition is
+// admitted even while another one is saturating the pool.
+type PartitionedBulkhead struct {
+	maxParallel     int           // 全局并发上限
+	guaranteed      int           // 每个分区保证的并发数
+	maxPerPartition int           // 单个分区的并发上限，0 表示只受全局上限限制
+	maxQueue        int           // 每个分区的队列长度
+	maxPartitions   int           // 最多跟踪的分区数，默认 10000
+	idleTimeout     time.Duration // 空闲分区的回收时间
+
+	keyFunc PartitionKeyFunc
+
+	mutex      sync.Mutex
+	active     int // 全局执行中的调用数
+	queued     int // 全局排队中的调用数
+	reserved   int // 有调用的分区尚未使用的保证配额之和
+	partitions map[string]*bulkheadPartition
+	waiting    map[*bulkheadPartition]struct{} // 有排队调用的分区，释放时只扫描它们
+	lastSweep  time.Time
+
+	onRejected OnBulkheadRejectedFunc
+
+	recoverPanics bool
+}
+
+// NewPartitionedBulkhead creates a partitioned bulkhead policy. Calls are
+// partitioned by PartitionKeyFromContext unless WithKeyFunc is used.
+func NewPartitionedBulkhead(maxParallel, guaranteed, maxQueue int) *PartitionedBulkhead {
+	if maxParallel <= 0 {
+		panic("maxParallel must be > 0")
+	}
+	if guaranteed < 0 || guaranteed > maxParallel {
+		panic("guaranteed must be between 0 and maxParallel")
+	}
+	if maxQueue < 0 {
+		panic("maxQueue must be >= 0")
+	}
+
+	return &PartitionedBulkhead{
+		maxParallel:   maxParallel,
+		guaranteed:    guaranteed,
+		maxQueue:      maxQueue,
+		maxPartitions: 10000,
+		idleTimeout:   5 * time.Minute,
+		keyFunc:       PartitionKeyFromContext,
+		partitions:    make(map[string]*bulkheadPartition),
+		waiting:       make(map[*bulkheadPartition]struct{}),
+	}
+}
+
+// WithKeyFunc configures how the partition key is extracted from ctx
+func (p *PartitionedBulkhead) WithKeyFunc(fn PartitionKeyFunc) *PartitionedBulkhead {
+	p.keyFunc = fn
+	return p
+}
+
+// WithMaxPerPartition caps the concurrency of any single partition
+func (p *PartitionedBulkhead) WithMaxPerPartition(n int) *PartitionedBulkhead {
+	p.maxPerPartition = n
+	return p
+}
+
+// WithMaxPartitions bounds the number of tracked partitions. A new key
+// beyond it replaces the least recently used idle partition, or is rejected
+// if every partition has calls.
+func (p *PartitionedBulkhead) WithMaxPartitions(n int) *PartitionedBulkhead {
+	if n <= 0 {
+		panic("maxPartitions must be > 0")
+	}
+	p.maxPartitions = n
+	return p
+}
+
+// WithIdleTimeout configures when an idle partition and its statistics are
+// removed. 默认 5 分钟。
+func (p *PartitionedBulkhead) WithIdleTimeout(d time.Duration) *PartitionedBulkhead {
+	p.idleTimeout = d
+	return p
+}
+
+// OnRejected sets the callback for rejections
+func (p *PartitionedBulkhead) OnRejected(fn OnBulkheadRejectedFunc) *PartitionedBulkhead {
+	p.onRejected = fn
+	return p
+}
+
+// InFlight returns the number of executions running across all partitions
+func (p *PartitionedBulkhead) InFlight() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.active
+}
+
+// Partitions returns a snapshot of every live partition
+func (p *PartitionedBulkhead) Partitions() map[string]PartitionStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	stats := make(map[string]PartitionStats, len(p.partitions))
+	for key, part := range p.partitions {
+		stats[key] = PartitionStats{
+			Active:   part.active,
+			Queued:   part.waiters.Len(),
+			Admitted: part.admitted,
+			Rejected: part.rejected,
+		}
+	}
+	return stats
+}
+
+// RecoverPanics converts panics in fn into a *PanicError, see Recover
+func (p *PartitionedBulkhead) RecoverPanics() *PartitionedBulkhead {
+	p.recoverPanics = true
+	return p
+}
+
+// Execute executes the given function within the partition of ctx
+func (p *PartitionedBulkhead) Execute(ctx context.Context, fn Func) error {
+	if p.recoverPanics {
+		fn = recoverFunc(fn)
+	}
+
+	part, err := p.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.release(part)
+
+	return fn(ctx)
+}
+
+func (p *PartitionedBulkhead) acquire(ctx context.Context) (*bulkheadPartition, error) {
+	key := p.keyFunc(ctx)
+	now := time.Now()
+
+	p.mutex.Lock()
+
+	if p.evictIdleLocked(now) {
+		p.admitLocked()
+	}
+	part := p.partitionLocked(key)
+	if part == nil {
+		// too many partitions, all of them busy
+		err := p.rejectionLocked(ctx, RejectPartitionLimit)
+		p.mutex.Unlock()
+		if p.onRejected != nil {
+			p.onRejected(ctx)
+		}
+		return nil, err
+	}
+	part.lastUsed = now
+
+	if part.waiters.Len() == 0 && p.canAdmitLocked(part) {
+		p.grantLocked(part)
+		p.mutex.Unlock()
+		return part, nil
+	}
+
+	if ctx.Err() != nil {
+		err := p.rejectionLocked(ctx, RejectCancelled)
+		p.mutex.Unlock()
+		return nil, err
+	}
+
+	if part.waiters.Len() >= p.maxQueue {
+		// queue full
+		part.rejected++
+		err := p.rejectionLocked(ctx, RejectQueueFull)
+		p.mutex.Unlock()
+		if p.onRejected != nil {
+			p.onRejected(ctx)
+		}
+		return nil, err
+	}
+
+	w := newBulkheadWaiter(ctx)
+	p.updateLocked(part, func() { w.elem = part.waiters.PushBack(w) })
+	p.mutex.Unlock()
+
+	select {
+	case <-w.ready:
+		return part, nil
+	case <-ctx.Done():
+		p.mutex.Lock()
+		select {
+		case <-w.ready:
+			// slot handed over concurrently, give it back
+			p.releaseLocked(part)
+		default:
+			p.updateLocked(part, func() { part.waiters.Remove(w.elem) })
+		}
+		err := p.rejectionLocked(ctx, RejectCancelled)
+		p.mutex.Unlock()
+		return nil, err
+	}
+}
+
+// rejectionLocked builds the rejection error with the occupancy of the whole
+// bulkhead; per-partition counts are available from Partitions
+func (p *PartitionedBulkhead) rejectionLocked(ctx context.Context, reason BulkheadRejectReason) error {
+	return &BulkheadRejectedError{
+		Reason:   reason,
+		InFlight: p.active,
+		Queued:   p.queued,
+		Err:      rejectReasonErr(ctx, reason),
+	}
+}
+
+func (p *PartitionedBulkhead) release(part *bulkheadPartition) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.releaseLocked(part)
+}
+
+func (p *PartitionedBulkhead) releaseLocked(part *bulkheadPartition) {
+	p.setActiveLocked(part, part.active-1)
+	part.lastUsed = time.Now()
+	p.admitLocked()
+}
+
+// partitionLocked returns the partition for key, creating it if needed.
+// It returns nil if the partition limit is reached and none is idle.
+func (p *PartitionedBulkhead) partitionLocked(key string) *bulkheadPartition {
+	if part, ok := p.partitions[key]; ok {
+		return part
+	}
+
+	if len(p.partitions) >= p.maxPartitions && !p.evictOldestLocked() {
+		return nil
+	}
+	part := &bulkheadPartition{}
+	p.partitions[key] = part
+	return part
+}
+
+// evictOldestLocked removes the least recently used idle partition
+func (p *PartitionedBulkhead) evictOldestLocked() bool {
+	var oldestKey string
+	var oldest *bulkheadPartition
+	for key, part := range p.partitions {
+		if part.active > 0 || part.waiters.Len() > 0 {
+			continue
+		}
+		if oldest == nil || part.lastUsed.Before(oldest.lastUsed) {
+			oldestKey, oldest = key, part
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+	delete(p.partitions, oldestKey)
+	return true
+}
+
+// canAdmitLocked reports whether part may take a slot now. Below its
+// guarantee a partition only needs a free slot; above it, it may only use
+// slots not reserved for other partitions, and must leave guaranteed slots
+// for a partition that has no calls yet.
+func (p *PartitionedBulkhead) canAdmitLocked(part *bulkheadPartition) bool {
+	if p.active >= p.maxParallel {
+		return false
+	}
+	if p.maxPerPartition > 0 && part.active >= p.maxPerPartition {
+		return false
+	}
+	if part.active < p.guaranteed {
+		return true
+	}
+	return p.active+p.reservedLocked()+p.guaranteed < p.maxParallel
+}
+
+// reservedLocked returns the slots held back for other partitions, never
+// more than are free
+func (p *PartitionedBulkhead) reservedLocked() int {
+	return min(p.reserved, p.maxParallel-p.active)
+}
+
+func (p *PartitionedBulkhead) grantLocked(part *bulkheadPartition) {
+	p.setActiveLocked(part, part.active+1)
+	part.admitted++
+}
+
+// setActiveLocked updates the active counters
+func (p *PartitionedBulkhead) setActiveLocked(part *bulkheadPartition, active int) {
+	p.updateLocked(part, func() {
+		p.active += active - part.active
+		part.active = active
+	})
+}
+
+// updateLocked applies a change to part's calls, keeping the unused
+// reservations and the set of waiting partitions in sync
+func (p *PartitionedBulkhead) updateLocked(part *bulkheadPartition, change func()) {
+	p.reserved -= p.unusedLocked(part)
+	p.queued -= part.waiters.Len()
+	change()
+	p.reserved += p.unusedLocked(part)
+	p.queued += part.waiters.Len()
+
+	if part.waiters.Len() > 0 {
+		p.waiting[part] = struct{}{}
+	} else {
+		delete(p.waiting, part)
+	}
+}
+
+// unusedLocked returns the guaranteed slots part holds but does not use.
+// 没有调用的分区不保留配额。
+func (p *PartitionedBulkhead) unusedLocked(part *bulkheadPartition) int {
+	if part.active == 0 && part.waiters.Len() == 0 {
+		return 0
+	}
+	if part.active >= p.guaranteed {
+		return 0
+	}
+	return p.guaranteed - part.active
+}
+
+// admitLocked hands free slots to queued calls, least loaded partition first
+func (p *PartitionedBulkhead) admitLocked() {
+	for {
+		var next *bulkheadPartition
+		for part := range p.waiting {
+			if !p.canAdmitLocked(part) {
+				continue
+			}
+			if next == nil || part.active < next.active {
+				next = part
+			}
+		}
+		if next == nil {
+			return
+		}
+
+		var w *bulkheadWaiter
+		p.updateLocked(next, func() {
+			w = next.waiters.Remove(next.waiters.Front()).(*bulkheadWaiter)
+		})
+		p.grantLocked(next)
+		close(w.ready)
+	}
+}
+
+// evictIdleLocked removes partitions idle for longer than idleTimeout and
+// reports whether any was removed. The sweep runs at most once per
+// idleTimeout.
+func (p *PartitionedBulkhead) evictIdleLocked(now time.Time) bool {
+	if p.idleTimeout <= 0 || now.Sub(p.lastSweep) < p.idleTimeout {
+		return false
+	}
+	p.lastSweep = now
+
+	evicted := false
+	for key, part := range p.partitions {
+		if part.active == 0 && part.waiters.Len() == 0 && now.Sub(part.lastUsed) >= p.idleTimeout {
+			p.reserved -= p.unusedLocked(part)
+			delete(p.partitions, key)
+			evicted = true
+		}
+	}
+	return evicted
+}
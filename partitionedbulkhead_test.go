@@ -0,0 +1,239 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 空闲分区不保留配额，活跃分区可以占用除余量外的整个共享池
+func TestPartitionedBulkhead_IdlePartitionsDoNotReserve(t *testing.T) {
+	pb := NewPartitionedBulkhead(4, 1, 0)
+	for _, key := range []string{"b", "c", "d", "e"} {
+		_ = pb.Execute(WithPartitionKey(context.Background(), key), func(ctx context.Context) error { return nil })
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	noisy := WithPartitionKey(context.Background(), "a")
+	for i := 0; i < 4; i++ {
+		go func() {
+			_ = pb.Execute(noisy, func(ctx context.Context) error {
+				<-release
+				return nil
+			})
+		}()
+	}
+
+	waitPartitions(t, pb, func(s map[string]PartitionStats) bool { return s["a"].Active+int(s["a"].Rejected) == 4 })
+	if a := pb.Partitions()["a"]; a.Active != 3 || a.Rejected != 1 {
+		t.Fatalf("expected a to burst into 3 slots, got %+v", a)
+	}
+}
+
+// 一个分区占满共享池时，新到达的分区仍能立即获得保证的槽位
+func TestPartitionedBulkhead_Headroom(t *testing.T) {
+	pb := NewPartitionedBulkhead(4, 1, 0)
+
+	release := make(chan struct{})
+	defer close(release)
+	noisy := WithPartitionKey(context.Background(), "a")
+	for i := 0; i < 4; i++ {
+		go func() {
+			_ = pb.Execute(noisy, func(ctx context.Context) error {
+				<-release
+				return nil
+			})
+		}()
+	}
+	waitPartitions(t, pb, func(s map[string]PartitionStats) bool { return s["a"].Active+int(s["a"].Rejected) == 4 })
+
+	go func() {
+		_ = pb.Execute(WithPartitionKey(context.Background(), "b"), func(ctx context.Context) error {
+			<-release
+			return nil
+		})
+	}()
+	waitPartitions(t, pb, func(s map[string]PartitionStats) bool { return s["b"].Active == 1 })
+
+	// 拒绝信息反映整个舱壁的占用，而不是新分区自身的
+	err := pb.Execute(WithPartitionKey(context.Background(), "c"), func(ctx context.Context) error { return nil })
+	var rejected *BulkheadRejectedError
+	if !errors.As(err, &rejected) || rejected.Reason != RejectQueueFull {
+		t.Fatalf("expected queue full rejection, got %v", err)
+	}
+	if rejected.InFlight != 4 || rejected.Queued != 0 {
+		t.Fatalf("expected global occupancy, got %+v", rejected)
+	}
+}
+
+// 排队中的分区保留配额，释放的槽位优先交给它
+func TestPartitionedBulkhead_Guaranteed(t *testing.T) {
+	pb := NewPartitionedBulkhead(4, 1, 1)
+	noisy := WithPartitionKey(context.Background(), "a")
+
+	releases := make([]chan struct{}, 4)
+	for i := range releases {
+		releases[i] = make(chan struct{})
+		release := releases[i]
+		ctx := noisy
+		if i == 3 {
+			ctx = WithPartitionKey(context.Background(), "b")
+		}
+		go func() {
+			_ = pb.Execute(ctx, func(ctx context.Context) error {
+				<-release
+				return nil
+			})
+		}()
+		waitPartitions(t, pb, func(s map[string]PartitionStats) bool { return s["a"].Active+s["b"].Active == i+1 })
+	}
+
+	aQueued := make(chan struct{})
+	cDone := make(chan error, 1)
+	go func() {
+		cDone <- pb.Execute(WithPartitionKey(context.Background(), "c"), func(ctx context.Context) error {
+			select {
+			case <-aQueued:
+				return errors.New("a's queued call ran before c")
+			default:
+				return nil
+			}
+		})
+	}()
+	waitPartitions(t, pb, func(s map[string]PartitionStats) bool { return s["c"].Queued == 1 })
+
+	go func() {
+		_ = pb.Execute(noisy, func(ctx context.Context) error {
+			close(aQueued)
+			return nil
+		})
+	}()
+	waitPartitions(t, pb, func(s map[string]PartitionStats) bool { return s["a"].Queued == 1 })
+
+	close(releases[0])
+	if err := <-cDone; err != nil {
+		t.Fatalf("expected c to get the freed slot first, got %v", err)
+	}
+
+	for _, release := range releases[1:] {
+		close(release)
+	}
+	<-aQueued
+}
+
+// 分区数量受限，满时淘汰最久未使用的空闲分区
+func TestPartitionedBulkhead_MaxPartitions(t *testing.T) {
+	pb := NewPartitionedBulkhead(2, 1, 0).WithMaxPartitions(2)
+	noop := func(ctx context.Context) error { return nil }
+
+	_ = pb.Execute(WithPartitionKey(context.Background(), "a"), noop)
+	_ = pb.Execute(WithPartitionKey(context.Background(), "b"), noop)
+	_ = pb.Execute(WithPartitionKey(context.Background(), "c"), noop)
+
+	stats := pb.Partitions()
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 partitions, got %v", stats)
+	}
+	if _, ok := stats["a"]; ok {
+		t.Fatal("expected oldest partition a to be evicted")
+	}
+
+	// 所有分区都有调用时拒绝新的 key
+	release := make(chan struct{})
+	defer close(release)
+	for _, key := range []string{"b", "c"} {
+		ctx := WithPartitionKey(context.Background(), key)
+		go func() {
+			_ = pb.Execute(ctx, func(ctx context.Context) error {
+				<-release
+				return nil
+			})
+		}()
+	}
+	deadline := time.Now().Add(time.Second)
+	for pb.InFlight() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	err := pb.Execute(WithPartitionKey(context.Background(), "d"), noop)
+	var rejected *BulkheadRejectedError
+	if !errors.Is(err, ErrBulkheadPartitionLimit) || !errors.As(err, &rejected) || rejected.Reason != RejectPartitionLimit {
+		t.Fatalf("expected partition limit rejection, got %v", err)
+	}
+	if rejected.InFlight != 2 {
+		t.Fatalf("expected global occupancy, got %+v", rejected)
+	}
+}
+
+// waitPartitions polls until cond holds
+func waitPartitions(t *testing.T, pb *PartitionedBulkhead, cond func(map[string]PartitionStats) bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond(pb.Partitions()) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out, partitions %+v", pb.Partitions())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// 排队的调用在释放后被放行
+func TestPartitionedBulkhead_Queue(t *testing.T) {
+	pb := NewPartitionedBulkhead(1, 0, 1)
+	ctx := WithPartitionKey(context.Background(), "a")
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_ = pb.Execute(ctx, func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pb.Execute(ctx, func(ctx context.Context) error { return nil })
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for pb.Partitions()["a"].Queued != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats := pb.Partitions()["a"]; stats.Admitted != 2 {
+		t.Fatalf("expected 2 admitted, got %+v", stats)
+	}
+
+	pb.mutex.Lock()
+	waiting := len(pb.waiting)
+	pb.mutex.Unlock()
+	if waiting != 0 {
+		t.Fatalf("expected no waiting partitions, got %d", waiting)
+	}
+}
+
+// 空闲分区被回收
+func TestPartitionedBulkhead_IdleEviction(t *testing.T) {
+	pb := NewPartitionedBulkhead(2, 1, 0).WithIdleTimeout(10 * time.Millisecond)
+
+	_ = pb.Execute(WithPartitionKey(context.Background(), "a"), func(ctx context.Context) error { return nil })
+	time.Sleep(20 * time.Millisecond)
+	_ = pb.Execute(WithPartitionKey(context.Background(), "b"), func(ctx context.Context) error { return nil })
+
+	stats := pb.Partitions()
+	if _, ok := stats["a"]; ok {
+		t.Fatalf("expected idle partition a to be evicted")
+	}
+	if _, ok := stats["b"]; !ok {
+		t.Fatalf("expected partition b to exist")
+	}
+}
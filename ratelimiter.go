@@ -0,0 +1,261 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimitedError is returned when a call is rejected by a rate limiter.
+// It matches ErrRateLimited with errors.Is.
+type RateLimitedError struct {
+	RetryAfter time.Duration // 预计可获得令牌的等待时间
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("%s, retry after %v", ErrRateLimited, e.RetryAfter)
+}
+
+func (e *RateLimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+type costKey struct{}
+
+// WithCost returns a ctx whose calls consume n tokens (or count n times
+// against a window limit) instead of one
+func WithCost(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, costKey{}, n)
+}
+
+// CostFromContext returns the cost set by WithCost, or 1
+func CostFromContext(ctx context.Context) int {
+	n, ok := ctx.Value(costKey{}).(int)
+	if !ok {
+		return 1
+	}
+	return n
+}
+
+// errCostTooHigh reports a cost the limiter can never admit. It matches
+// ErrRateLimited and is permanent, so Retry does not wait for it.
+func errCostTooHigh(cost, capacity int) error {
+	return Permanent(fmt.Errorf("%w: cost %d exceeds capacity %d", ErrRateLimited, cost, capacity))
+}
+
+// OnRateLimitedFunc is called when a call is rejected
+type OnRateLimitedFunc func(retryAfter time.Duration, ctx context.Context)
+
+// warmupColdFactor is the fraction of the rate at the start of a warm-up
+const warmupColdFactor = 1.0 / 3
+
+// RateLimiter implements the Resilience interface with a token bucket.
+// Tokens refill at rate per second up to burst. By default calls without
+// enough tokens are rejected; WithMaxWait makes them wait instead.
+type RateLimiter struct {
+	rate    float64       // 每秒补充的令牌数
+	burst   int           // 桶容量
+	maxWait time.Duration // 等待令牌的最长时间，0 表示直接拒绝
+	warmup  time.Duration // 启动后从 1/3 速率线性升至 rate 的时间
+
+	weigher func(ctx context.Context) int // tokens consumed by a call, see WithWeigher
+
+	mutex  sync.Mutex
+	tokens float64 // may go negative while waiters hold reservations
+	last   time.Time
+	start  time.Time
+
+	onRejected OnRateLimitedFunc
+
+	recoverPanics bool
+}
+
+// NewRateLimiter creates a token bucket rate limiter policy. The bucket
+// starts full.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if rate <= 0 {
+		panic("rate must be > 0")
+	}
+	if burst <= 0 {
+		panic("burst must be > 0")
+	}
+
+	now := time.Now()
+	return &RateLimiter{
+		rate:   rate,
+		burst:  burst,
+		tokens: float64(burst),
+		last:   now,
+		start:  now,
+	}
+}
+
+// WithMaxWait makes calls wait up to d for tokens instead of being
+// rejected. Calls whose ctx ends before the tokens are available are
+// rejected without waiting.
+func (r *RateLimiter) WithMaxWait(d time.Duration) *RateLimiter {
+	r.maxWait = d
+	return r
+}
+
+// WithWarmup ramps the rate linearly from a third of the configured rate
+// to the full rate over d, starting from an empty bucket, so a cold
+// dependency is not hit with a full burst right after start.
+func (r *RateLimiter) WithWarmup(d time.Duration) *RateLimiter {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.warmup = d
+	if d > 0 {
+		r.tokens = 0
+	}
+	return r
+}
+
+// WithWeigher configures how many tokens a call consumes, overriding
+// WithCost. Calls costing more than burst can never be admitted and are
+// rejected with a permanent error matching ErrRateLimited.
+func (r *RateLimiter) WithWeigher(fn func(ctx context.Context) int) *RateLimiter {
+	r.weigher = fn
+	return r
+}
+
+// OnRejected sets the callback for rejected calls
+func (r *RateLimiter) OnRejected(fn OnRateLimitedFunc) *RateLimiter {
+	r.onRejected = fn
+	return r
+}
+
+// RecoverPanics converts panics in fn into a *PanicError, see Recover
+func (r *RateLimiter) RecoverPanics() *RateLimiter {
+	r.recoverPanics = true
+	return r
+}
+
+// Tokens returns the number of tokens currently available
+func (r *RateLimiter) Tokens() float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.advanceLocked(time.Now())
+	return math.Max(0, r.tokens)
+}
+
+func (r *RateLimiter) Execute(ctx context.Context, fn Func) error {
+	if r.recoverPanics {
+		fn = recoverFunc(fn)
+	}
+
+	if err := r.acquire(ctx); err != nil {
+		return err
+	}
+	return fn(ctx)
+}
+
+func (r *RateLimiter) acquire(ctx context.Context) error {
+	cost := r.costFor(ctx)
+	if cost > r.burst {
+		return errCostTooHigh(cost, r.burst)
+	}
+	now := time.Now()
+
+	r.mutex.Lock()
+	wait, ok := r.reserveLocked(now, cost, r.waitLimit(ctx, now))
+	r.mutex.Unlock()
+
+	if !ok {
+		return r.reject(ctx, wait)
+	}
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// give the reserved tokens back
+		r.mutex.Lock()
+		r.tokens = math.Min(float64(r.burst), r.tokens+float64(cost))
+		r.mutex.Unlock()
+		return ctx.Err()
+	}
+}
+
+// waitLimit is the longest a call may wait: maxWait, shortened by ctx
+func (r *RateLimiter) waitLimit(ctx context.Context, now time.Time) time.Duration {
+	limit := r.maxWait
+	if deadline, ok := ctx.Deadline(); ok && deadline.Sub(now) < limit {
+		limit = deadline.Sub(now)
+	}
+	return limit
+}
+
+// reserveLocked takes cost tokens if they are available within maxWait.
+// It returns how long to wait for them, or on failure the retry-after hint.
+func (r *RateLimiter) reserveLocked(now time.Time, cost int, maxWait time.Duration) (time.Duration, bool) {
+	r.advanceLocked(now)
+
+	deficit := float64(cost) - r.tokens
+	if deficit <= 0 {
+		r.tokens -= float64(cost)
+		return 0, true
+	}
+
+	wait := time.Duration(deficit / r.rateAt(now) * float64(time.Second))
+	if wait > maxWait {
+		return wait, false
+	}
+
+	r.tokens -= float64(cost)
+	return wait, true
+}
+
+// advanceLocked refills the bucket for the time elapsed since the last call
+func (r *RateLimiter) advanceLocked(now time.Time) {
+	elapsed := now.Sub(r.last)
+	if elapsed <= 0 {
+		return
+	}
+	r.last = now
+
+	r.tokens = math.Min(float64(r.burst), r.tokens+elapsed.Seconds()*r.rateAt(now))
+}
+
+// rateAt returns the refill rate, lowered during the warm-up period
+func (r *RateLimiter) rateAt(now time.Time) float64 {
+	since := now.Sub(r.start)
+	if r.warmup <= 0 || since >= r.warmup {
+		return r.rate
+	}
+
+	progress := float64(since) / float64(r.warmup)
+	return r.rate * (warmupColdFactor + (1-warmupColdFactor)*progress)
+}
+
+// costFor returns the tokens consumed by a call
+func (r *RateLimiter) costFor(ctx context.Context) int {
+	n := CostFromContext(ctx)
+	if r.weigher != nil {
+		n = r.weigher(ctx)
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+func (r *RateLimiter) reject(ctx context.Context, retryAfter time.Duration) error {
+	if r.onRejected != nil {
+		r.onRejected(retryAfter, ctx)
+	}
+	return &RateLimitedError{RetryAfter: retryAfter}
+}
@@ -0,0 +1,124 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func noop(ctx context.Context) error { return nil }
+
+// 令牌耗尽后拒绝，并给出重试等待时间
+func TestRateLimiter_Reject(t *testing.T) {
+	rl := NewRateLimiter(10, 2)
+
+	for i := 0; i < 2; i++ {
+		if err := rl.Execute(context.Background(), noop); err != nil {
+			t.Fatalf("call %d: unexpected error %v", i, err)
+		}
+	}
+
+	var hint time.Duration
+	rl.OnRejected(func(retryAfter time.Duration, ctx context.Context) { hint = retryAfter })
+
+	err := rl.Execute(context.Background(), noop)
+	var limited *RateLimitedError
+	if !errors.Is(err, ErrRateLimited) || !errors.As(err, &limited) {
+		t.Fatalf("expected rate limited, got %v", err)
+	}
+	if limited.RetryAfter <= 0 || limited.RetryAfter > 100*time.Millisecond || hint != limited.RetryAfter {
+		t.Fatalf("unexpected retry-after %v (callback %v)", limited.RetryAfter, hint)
+	}
+
+	time.Sleep(limited.RetryAfter + 10*time.Millisecond)
+	if err := rl.Execute(context.Background(), noop); err != nil {
+		t.Fatalf("expected token after retry-after, got %v", err)
+	}
+}
+
+// 加权调用消耗多个令牌
+func TestRateLimiter_WeightedCost(t *testing.T) {
+	rl := NewRateLimiter(1, 5)
+
+	if err := rl.Execute(WithCost(context.Background(), 4), noop); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := rl.Execute(WithCost(context.Background(), 2), noop); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected rate limited, got %v", err)
+	}
+	if err := rl.Execute(context.Background(), noop); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// 超过桶容量的调用永远无法通过，直接以永久错误拒绝
+	rl = NewRateLimiter(1, 5)
+	err := rl.Execute(WithCost(context.Background(), 6), noop)
+	if !errors.Is(err, ErrRateLimited) || !IsPermanent(err) {
+		t.Fatalf("expected permanent rate limited error, got %v", err)
+	}
+	if tokens := rl.Tokens(); tokens != 5 {
+		t.Fatalf("rejected call should not consume tokens, have %v", tokens)
+	}
+}
+
+// 等待模式在最长等待时间内等待令牌
+func TestRateLimiter_Wait(t *testing.T) {
+	rl := NewRateLimiter(50, 1).WithMaxWait(100 * time.Millisecond)
+	_ = rl.Execute(context.Background(), noop)
+
+	start := time.Now()
+	if err := rl.Execute(context.Background(), noop); err != nil {
+		t.Fatalf("expected to wait for a token, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Fatalf("expected to wait ~20ms, waited %v", elapsed)
+	}
+
+	// 需要等待超过上限时直接拒绝
+	slow := NewRateLimiter(1, 1).WithMaxWait(10 * time.Millisecond)
+	_ = slow.Execute(context.Background(), noop)
+	if err := slow.Execute(context.Background(), noop); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected rate limited, got %v", err)
+	}
+}
+
+// 等待时遵守 ctx 的截止时间与取消
+func TestRateLimiter_WaitRespectsContext(t *testing.T) {
+	rl := NewRateLimiter(10, 1).WithMaxWait(time.Second)
+	_ = rl.Execute(context.Background(), noop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := rl.Execute(ctx, noop); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected rejection within ctx deadline, got %v", err)
+	}
+	if time.Since(start) > 10*time.Millisecond {
+		t.Fatal("should reject without waiting")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := rl.Execute(ctx, noop); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+	if tokens := rl.Tokens(); tokens > 1 {
+		t.Fatalf("refund exceeded burst, have %v tokens", tokens)
+	}
+}
+
+// 预热期间速率较低
+func TestRateLimiter_Warmup(t *testing.T) {
+	rl := NewRateLimiter(100, 100).WithWarmup(time.Hour)
+
+	if err := rl.Execute(context.Background(), noop); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected empty bucket at start, got %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	// 冷启动速率约为 33/s，60ms 约补充 2 个令牌，而不是 6 个
+	if tokens := rl.Tokens(); tokens < 1 || tokens > 4 {
+		t.Fatalf("expected ~2 tokens during warm-up, got %.2f", tokens)
+	}
+}
@@ -0,0 +1,74 @@
+package resilience
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is a panic in fn converted into an error, so Retry,
+// CircuitBreaker and Fallback can handle it like any other failure.
+// If the panic value is an error, errors.Is / errors.As match it.
+type PanicError struct {
+	Value any    // recover() 的返回值
+	Stack []byte // panic 时的调用栈
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// newPanicError captures the stack; call it from the deferred recover
+func newPanicError(value any) *PanicError {
+	return &PanicError{Value: value, Stack: debug.Stack()}
+}
+
+// recoverFunc wraps fn so a panic is returned as a *PanicError
+func recoverFunc(fn Func) Func {
+	return func(ctx context.Context) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = newPanicError(r)
+			}
+		}()
+		return fn(ctx)
+	}
+}
+
+type OnPanicFunc func(err *PanicError, ctx context.Context)
+
+// Recover implements the Resilience interface. It converts panics in fn
+// into a *PanicError. Place it inside policies that should see panics as
+// errors, e.g. Wrap(retry, breaker, NewRecover()).
+type Recover struct {
+	onPanic OnPanicFunc
+}
+
+// NewRecover creates a panic isolation policy
+func NewRecover() *Recover {
+	return &Recover{}
+}
+
+// OnPanic sets the callback for recovered panics
+func (r *Recover) OnPanic(fn OnPanicFunc) *Recover {
+	r.onPanic = fn
+	return r
+}
+
+func (r *Recover) Execute(ctx context.Context, fn Func) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			p := newPanicError(v)
+			if r.onPanic != nil {
+				r.onPanic(p, ctx)
+			}
+			err = p
+		}
+	}()
+	return fn(ctx)
+}
@@ -0,0 +1,117 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Recover 将 panic 转换为包含值和调用栈的 PanicError
+func TestRecover_ConvertsPanic(t *testing.T) {
+	var seen *PanicError
+	r := NewRecover().OnPanic(func(err *PanicError, ctx context.Context) {
+		seen = err
+	})
+
+	err := r.Execute(context.Background(), func(ctx context.Context) error {
+		panic("boom")
+	})
+
+	var p *PanicError
+	if !errors.As(err, &p) || p.Value != "boom" {
+		t.Fatalf("expected PanicError, got %v", err)
+	}
+	if !strings.Contains(string(p.Stack), "TestRecover_ConvertsPanic") {
+		t.Fatalf("stack should include the panic site:\n%s", p.Stack)
+	}
+	if seen != p {
+		t.Fatal("OnPanic should receive the PanicError")
+	}
+}
+
+// panic 的值为 error 时可通过 errors.Is 匹配
+func TestRecover_UnwrapsErrorValue(t *testing.T) {
+	sentinel := errors.New("sentinel")
+	err := NewRecover().Execute(context.Background(), func(ctx context.Context) error {
+		panic(sentinel)
+	})
+	if !errors.Is(err, sentinel) {
+		t.Fatalf("expected to match panic value, got %v", err)
+	}
+}
+
+// Retry 像处理普通错误一样重试 panic
+func TestRetry_RecoverPanics(t *testing.T) {
+	var calls int32
+	err := NewRetry(2).RecoverPanics().Execute(context.Background(), func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			panic("flaky")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("expected success after 3 calls, got %v after %d", err, calls)
+	}
+}
+
+// panic 计为熔断器失败
+func TestCircuitBreaker_RecoverPanics(t *testing.T) {
+	cb := NewCircuitBreaker(2, time.Minute).RecoverPanics()
+	for i := 0; i < 2; i++ {
+		_ = cb.Execute(context.Background(), func(ctx context.Context) error { panic("boom") })
+	}
+
+	err := cb.Execute(context.Background(), func(ctx context.Context) error { return nil })
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open circuit, got %v", err)
+	}
+}
+
+// Fallback 收到 PanicError
+func TestFallback_RecoverPanics(t *testing.T) {
+	f := NewFallbackFunc(func(ctx context.Context, err error) error {
+		var p *PanicError
+		if !errors.As(err, &p) {
+			t.Errorf("expected PanicError, got %v", err)
+		}
+		return nil
+	}).RecoverPanics()
+
+	if err := f.Execute(context.Background(), func(ctx context.Context) error { panic("boom") }); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+// Pessimistic：被放弃执行中的 panic 以 PanicError 上报
+func TestTimeout_AbandonedPanicError(t *testing.T) {
+	reported := make(chan error, 1)
+	to := NewTimeout(10 * time.Millisecond).
+		WithMode(Pessimistic).
+		OnAbandonedCompleted(func(err error, elapsed time.Duration) {
+			reported <- err
+		})
+
+	_ = to.Execute(context.Background(), func(ctx context.Context) error {
+		time.Sleep(30 * time.Millisecond)
+		panic("late")
+	})
+
+	var p *PanicError
+	if err := <-reported; !errors.As(err, &p) || p.Value != "late" {
+		t.Fatalf("expected PanicError, got %v", err)
+	}
+}
+
+// Pessimistic + RecoverPanics：调用方得到错误而不是 panic
+func TestTimeout_RecoverPanics(t *testing.T) {
+	to := NewTimeout(time.Second).WithMode(Pessimistic).RecoverPanics()
+
+	err := to.Execute(context.Background(), func(ctx context.Context) error { panic("boom") })
+	var p *PanicError
+	if !errors.As(err, &p) {
+		t.Fatalf("expected PanicError, got %v", err)
+	}
+}
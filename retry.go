@@ -2,6 +2,7 @@ package resilience
 
 import (
 	"context"
+	"errors"
 	"math/rand"
 	"time"
 )
@@ -23,6 +24,8 @@ type Retry struct {
 	shouldRetry func(error) bool
 	backoff     BackoffStrategy
 	onRetry     OnRetryFunc
+
+	recoverPanics bool
 }
 
 // OnRetryFunc mirrors Polly's OnRetry callback
@@ -42,11 +45,9 @@ type OnRetryFunc func(
 // New creates a retry policy with max retries
 func NewRetry(maxRetries int) *Retry {
 	return &Retry{
-		maxRetries: maxRetries,
-		shouldRetry: func(err error) bool {
-			return err != nil
-		},
-		backoff: NoBackoff{},
+		maxRetries:  maxRetries,
+		shouldRetry: defaultRetryHandle,
+		backoff:     NoBackoff{},
 	}
 }
 
@@ -54,13 +55,17 @@ func NewRetry(maxRetries int) *Retry {
 func Forever() *Retry {
 	return &Retry{
 		retryForever: true,
-		shouldRetry: func(err error) bool {
-			return err != nil
-		},
-		backoff: NoBackoff{},
+		shouldRetry:  defaultRetryHandle,
+		backoff:      NoBackoff{},
 	}
 }
 
+// defaultRetryHandle is defaultHandle without ErrBudgetExhausted: the
+// deadline budget only shrinks, so retrying cannot help
+func defaultRetryHandle(err error) bool {
+	return defaultHandle(err) && !errors.Is(err, ErrBudgetExhausted)
+}
+
 /*
 ========================
  Fluent Configuration
@@ -85,8 +90,18 @@ func (r *Retry) OnRetry(f OnRetryFunc) *Retry {
 	return r
 }
 
+// RecoverPanics converts panics in fn into a *PanicError, see Recover
+func (r *Retry) RecoverPanics() *Retry {
+	r.recoverPanics = true
+	return r
+}
+
 // Execute retries based on error predicate
 func (r *Retry) Execute(ctx context.Context, fn Func) error {
+	if r.recoverPanics {
+		fn = recoverFunc(fn)
+	}
+
 	var err error
 	attempt := 0 // 记录重试次数
 
@@ -100,7 +115,11 @@ func (r *Retry) Execute(ctx context.Context, fn Func) error {
 			return nil
 		}
 
-		if !r.shouldRetry(err) {
+		// Permanent / Transient markers take precedence over the predicate
+		if IsPermanent(err) {
+			return err
+		}
+		if !IsTransient(err) && !r.shouldRetry(err) {
 			return err
 		}
 
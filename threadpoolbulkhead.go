@@ -0,0 +1,422 @@
+package resilience
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ThreadPoolBulkhead implements the Resilience interface. Unlike Bulkhead,
+// fn runs on a bounded pool of long-lived worker goroutines rather than on
+// the caller's goroutine, so a caller whose ctx is done can walk away from a
+// hung dependency (Hystrix thread-pool isolation).
+type ThreadPoolBulkhead struct {
+	workers        int           // worker goroutines
+	maxQueue       int           // tasks waiting for a worker
+	stuckThreshold time.Duration // workers busy longer than this are replaced, 0 disables
+	maxStuck       int           // replaced workers allowed to linger, defaults to workers
+
+	closePolicy BulkheadClosePolicy // what Close does with queued tasks
+
+	tasks chan *poolTask
+
+	mutex    sync.Mutex
+	closed   bool
+	drained  chan struct{} // closed once a closed pool has no tasks left
+	pool     map[*poolWorker]struct{}
+	pending  int // submitted tasks not yet finished, queued or running
+	busy     int
+	stuck    int    // retired workers still running their task
+	recycled uint64 // workers replaced so far
+	rejected uint64
+
+	done chan struct{} // closed by Close, stops the supervisor
+
+	onRejected           OnBulkheadRejectedFunc
+	onAbandonedCompleted OnAbandonedCompletedFunc
+
+	recoverPanics bool
+
+	once sync.Once // for lazy initialization
+}
+
+// ThreadPoolStats is a point-in-time snapshot of a ThreadPoolBulkhead
+type ThreadPoolStats struct {
+	Workers    int     // 可用的 worker 数
+	Busy       int     // 正在执行的 worker 数（不含被替换的）
+	Saturation float64 // Busy / Workers
+	Queued     int     // 等待 worker 的任务数
+	Stuck      int     // 已被替换但仍在执行的 worker 数，不超过 maxStuck
+	Recycled   uint64  // 累计替换的 worker 数
+	Rejected   uint64  // 累计拒绝数
+}
+
+// poolTask is a single execution submitted to the pool
+type poolTask struct {
+	ctx    context.Context
+	fn     Func
+	state  atomic.Int32 // taskQueued → taskRunning → taskDone | taskAbandoned, or taskQueued → taskCancelled
+	result chan pessimisticResult
+}
+
+const (
+	taskQueued int32 = iota
+	taskRunning
+	taskDone
+	taskCancelled // caller left before the task started
+	taskAbandoned // caller left while the task was running
+)
+
+// poolWorker is a long-lived goroutine of the pool
+type poolWorker struct {
+	busySince atomic.Int64 // start of the current task in Unix nanoseconds, 0 when idle
+	retired   atomic.Bool  // replaced by the supervisor, exits after its current task
+}
+
+// NewThreadPoolBulkhead creates a thread-pool bulkhead policy
+func NewThreadPoolBulkhead(workers, maxQueue int) *ThreadPoolBulkhead {
+	if workers <= 0 {
+		panic("workers must be > 0")
+	}
+	if maxQueue < 0 {
+		panic("maxQueue must be >= 0")
+	}
+
+	return &ThreadPoolBulkhead{
+		workers:  workers,
+		maxQueue: maxQueue,
+		maxStuck: workers,
+		pool:     make(map[*poolWorker]struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// WithStuckThreshold replaces workers busy for longer than d with fresh
+// ones, so hung tasks do not shrink the pool. 被替换的 worker 在任务结束后退出。
+func (p *ThreadPoolBulkhead) WithStuckThreshold(d time.Duration) *ThreadPoolBulkhead {
+	if d < 0 {
+		panic("stuckThreshold must be >= 0")
+	}
+	p.stuckThreshold = d
+	return p
+}
+
+// WithMaxStuck bounds the replaced workers still running a hung task.
+// Once reached, stuck workers are no longer replaced, so the pool shrinks
+// and new calls are rejected instead of piling up goroutines. 默认等于 workers。
+func (p *ThreadPoolBulkhead) WithMaxStuck(n int) *ThreadPoolBulkhead {
+	if n < 0 {
+		panic("maxStuck must be >= 0")
+	}
+	p.maxStuck = n
+	return p
+}
+
+// WithClosePolicy configures what Close does with queued tasks.
+// 默认 CloseRejectQueued。
+func (p *ThreadPoolBulkhead) WithClosePolicy(policy BulkheadClosePolicy) *ThreadPoolBulkhead {
+	p.closePolicy = policy
+	return p
+}
+
+// OnAbandonedCompleted sets the callback for tasks that complete (or
+// panic) after their caller stopped waiting for them.
+func (p *ThreadPoolBulkhead) OnAbandonedCompleted(fn OnAbandonedCompletedFunc) *ThreadPoolBulkhead {
+	p.onAbandonedCompleted = fn
+	return p
+}
+
+// OnRejected sets the callback for rejections
+func (p *ThreadPoolBulkhead) OnRejected(fn OnBulkheadRejectedFunc) *ThreadPoolBulkhead {
+	p.onRejected = fn
+	return p
+}
+
+// Stats returns a snapshot of the pool
+func (p *ThreadPoolBulkhead) Stats() ThreadPoolStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	stats := ThreadPoolStats{
+		Workers:  len(p.pool),
+		Busy:     p.busy - p.stuck,
+		Queued:   p.pending - p.busy,
+		Stuck:    p.stuck,
+		Recycled: p.recycled,
+		Rejected: p.rejected,
+	}
+	if stats.Workers > 0 {
+		stats.Saturation = float64(stats.Busy) / float64(stats.Workers)
+	}
+	return stats
+}
+
+// RecoverPanics converts panics in fn into a *PanicError, see Recover
+func (p *ThreadPoolBulkhead) RecoverPanics() *ThreadPoolBulkhead {
+	p.recoverPanics = true
+	return p
+}
+
+// Execute submits fn to the pool and waits for its result or for ctx.
+// When ctx is done first the caller returns at once; a task that has not
+// started yet is skipped, a running task is left to finish on its worker.
+func (p *ThreadPoolBulkhead) Execute(ctx context.Context, fn Func) error {
+	if p.recoverPanics {
+		fn = recoverFunc(fn)
+	}
+
+	p.init()
+
+	if ctx.Err() != nil {
+		return p.rejection(ctx, RejectCancelled)
+	}
+
+	t := &poolTask{
+		ctx:    ctx,
+		fn:     fn,
+		result: make(chan pessimisticResult, 1),
+	}
+
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return p.reject(ctx, RejectClosed)
+	}
+	if p.pending-p.stuck >= p.workers+p.maxQueue {
+		// all workers busy and queue full
+		p.mutex.Unlock()
+		return p.reject(ctx, RejectQueueFull)
+	}
+	p.pending++
+	p.tasks <- t
+	p.mutex.Unlock()
+
+	select {
+	case res := <-t.result:
+		return res.unwrap()
+	case <-ctx.Done():
+		if t.state.CompareAndSwap(taskQueued, taskCancelled) {
+			return p.rejection(ctx, RejectCancelled)
+		}
+		if t.state.CompareAndSwap(taskRunning, taskAbandoned) {
+			return ctx.Err()
+		}
+		// completed in the meantime
+		return (<-t.result).unwrap()
+	}
+}
+
+// Close stops accepting tasks, handles queued tasks according to the close
+// policy, then waits until running tasks finish or ctx is done. Calls
+// arriving after Close are rejected with ErrBulkheadClosed. Workers exit
+// once the queue is empty. Close may be called more than once, for example
+// to wait again after ctx expired.
+func (p *ThreadPoolBulkhead) Close(ctx context.Context) error {
+	p.init()
+
+	var rejected []*poolTask
+
+	p.mutex.Lock()
+	if !p.closed {
+		p.closed = true
+		p.drained = make(chan struct{})
+		close(p.done)
+
+		if p.closePolicy == CloseRejectQueued {
+			rejected = p.takeQueuedLocked()
+		}
+		close(p.tasks)
+		p.checkDrainedLocked()
+	}
+	drained := p.drained
+	p.mutex.Unlock()
+
+	for _, t := range rejected {
+		t.result <- pessimisticResult{err: p.reject(t.ctx, RejectClosed)}
+	}
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// takeQueuedLocked removes the tasks no worker has picked up yet
+func (p *ThreadPoolBulkhead) takeQueuedLocked() []*poolTask {
+	var queued []*poolTask
+	for {
+		select {
+		case t := <-p.tasks:
+			p.pending--
+			if t.state.CompareAndSwap(taskQueued, taskCancelled) {
+				queued = append(queued, t)
+			}
+		default:
+			return queued
+		}
+	}
+}
+
+// checkDrainedLocked signals Close once nothing is running or queued
+func (p *ThreadPoolBulkhead) checkDrainedLocked() {
+	if !p.closed || p.pending > 0 {
+		return
+	}
+	select {
+	case <-p.drained:
+	default:
+		close(p.drained)
+	}
+}
+
+func (p *ThreadPoolBulkhead) init() {
+	p.once.Do(func() {
+		// pending 限制保证发送不会阻塞
+		p.tasks = make(chan *poolTask, p.workers+p.maxQueue)
+
+		p.mutex.Lock()
+		for i := 0; i < p.workers; i++ {
+			p.spawnLocked()
+		}
+		p.mutex.Unlock()
+
+		if p.stuckThreshold > 0 {
+			go p.supervise()
+		}
+	})
+}
+
+// spawnLocked starts a new worker
+func (p *ThreadPoolBulkhead) spawnLocked() {
+	w := &poolWorker{}
+	p.pool[w] = struct{}{}
+	go p.work(w)
+}
+
+func (p *ThreadPoolBulkhead) work(w *poolWorker) {
+	for t := range p.tasks {
+		p.mutex.Lock()
+		if !t.state.CompareAndSwap(taskQueued, taskRunning) {
+			// caller already walked away
+			p.pending--
+			p.checkDrainedLocked()
+			p.mutex.Unlock()
+			continue
+		}
+		p.busy++
+		p.mutex.Unlock()
+
+		start := time.Now()
+		w.busySince.Store(start.UnixNano())
+		p.complete(t, runTask(t), time.Since(start))
+		w.busySince.Store(0)
+
+		p.mutex.Lock()
+		p.pending--
+		p.busy--
+		p.checkDrainedLocked()
+		if w.retired.Load() {
+			p.stuck--
+			p.mutex.Unlock()
+			return
+		}
+		p.mutex.Unlock()
+	}
+
+	p.mutex.Lock()
+	delete(p.pool, w)
+	p.mutex.Unlock()
+}
+
+// complete hands the result to the caller, or to OnAbandonedCompleted if
+// the caller already left
+func (p *ThreadPoolBulkhead) complete(t *poolTask, res pessimisticResult, elapsed time.Duration) {
+	if t.state.CompareAndSwap(taskRunning, taskDone) {
+		t.result <- res
+		return
+	}
+
+	if p.onAbandonedCompleted != nil {
+		err := res.err
+		if res.panic != nil {
+			err = res.panic
+		}
+		p.onAbandonedCompleted(err, elapsed)
+	}
+}
+
+// runTask runs fn, capturing a panic so the caller can re-raise it
+func runTask(t *poolTask) (res pessimisticResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			res.panic = newPanicError(r)
+		}
+	}()
+	res.err = t.fn(t.ctx)
+	return res
+}
+
+// supervise replaces workers stuck on a task beyond the threshold
+func (p *ThreadPoolBulkhead) supervise() {
+	ticker := time.NewTicker(max(p.stuckThreshold/2, time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-ticker.C:
+			p.recycleStuck(now)
+		}
+	}
+}
+
+func (p *ThreadPoolBulkhead) recycleStuck(now time.Time) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for w := range p.pool {
+		if p.stuck >= p.maxStuck {
+			// 达到上限后不再替换，避免 goroutine 无限增长
+			return
+		}
+
+		since := w.busySince.Load()
+		if since == 0 || now.Sub(time.Unix(0, since)) < p.stuckThreshold {
+			continue
+		}
+
+		w.retired.Store(true)
+		delete(p.pool, w)
+		p.stuck++
+		p.recycled++
+		p.spawnLocked()
+	}
+}
+
+// reject counts and reports a rejection
+func (p *ThreadPoolBulkhead) reject(ctx context.Context, reason BulkheadRejectReason) error {
+	p.mutex.Lock()
+	p.rejected++
+	p.mutex.Unlock()
+
+	if p.onRejected != nil {
+		p.onRejected(ctx)
+	}
+	return p.rejection(ctx, reason)
+}
+
+func (p *ThreadPoolBulkhead) rejection(ctx context.Context, reason BulkheadRejectReason) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return &BulkheadRejectedError{
+		Reason:   reason,
+		InFlight: p.busy,
+		Queued:   p.pending - p.busy,
+		Err:      rejectReasonErr(ctx, reason),
+	}
+}
@@ -0,0 +1,293 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitPool polls the pool until cond holds
+func waitPool(t *testing.T, p *ThreadPoolBulkhead, cond func(ThreadPoolStats) bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond(p.Stats()) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for pool, stats %+v", p.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// 任务在 worker 上执行，结果返回给调用方
+func TestThreadPoolBulkhead_Execute(t *testing.T) {
+	p := NewThreadPoolBulkhead(2, 0)
+	defer p.Close(context.Background())
+
+	want := errors.New("boom")
+	if err := p.Execute(context.Background(), func(ctx context.Context) error { return want }); err != want {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+	if s := p.Stats(); s.Workers != 2 || s.Busy != 0 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+// worker 全忙且队列已满时拒绝，并报告饱和度
+func TestThreadPoolBulkhead_Saturation(t *testing.T) {
+	p := NewThreadPoolBulkhead(1, 1)
+	defer p.Close(context.Background())
+
+	release := make(chan struct{})
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			done <- p.Execute(context.Background(), func(ctx context.Context) error {
+				<-release
+				return nil
+			})
+		}()
+		waitPool(t, p, func(s ThreadPoolStats) bool { return s.Busy+s.Queued == i+1 })
+	}
+
+	if s := p.Stats(); s.Saturation != 1 || s.Queued != 1 {
+		t.Fatalf("expected saturated pool, got %+v", s)
+	}
+
+	err := p.Execute(context.Background(), func(ctx context.Context) error { return nil })
+	var rejected *BulkheadRejectedError
+	if !errors.As(err, &rejected) || rejected.Reason != RejectQueueFull {
+		t.Fatalf("expected queue full rejection, got %v", err)
+	}
+	if rejected.InFlight != 1 || rejected.Queued != 1 {
+		t.Fatalf("unexpected occupancy %+v", rejected)
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+}
+
+// 调用方 ctx 结束后立即返回，排队中的任务不再执行
+func TestThreadPoolBulkhead_CallerWalksAway(t *testing.T) {
+	p := NewThreadPoolBulkhead(1, 1)
+	defer p.Close(context.Background())
+
+	release := make(chan struct{})
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := p.Execute(ctx, func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatal("caller was not released")
+	}
+
+	// 唯一的 worker 仍被占用，新任务排队后随 ctx 取消
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel2()
+	ran := false
+	err = p.Execute(ctx2, func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+	var rejected *BulkheadRejectedError
+	if !errors.As(err, &rejected) || rejected.Reason != RejectCancelled {
+		t.Fatalf("expected cancelled rejection, got %v", err)
+	}
+	if ran {
+		t.Fatal("cancelled task should not run")
+	}
+}
+
+// 卡住的 worker 被替换，池容量恢复
+func TestThreadPoolBulkhead_RecycleStuck(t *testing.T) {
+	p := NewThreadPoolBulkhead(1, 0).WithStuckThreshold(20 * time.Millisecond)
+	defer p.Close(context.Background())
+
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_ = p.Execute(ctx, func(context.Context) error {
+			<-release
+			return nil
+		})
+	}()
+
+	waitPool(t, p, func(s ThreadPoolStats) bool { return s.Recycled == 1 })
+	cancel()
+
+	if s := p.Stats(); s.Workers != 1 || s.Stuck != 1 || s.Busy != 0 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	waitPool(t, p, func(s ThreadPoolStats) bool { return s.Busy == 0 })
+	if err := p.Execute(context.Background(), func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatalf("expected replacement worker to serve, got %v", err)
+	}
+
+	close(release)
+	waitPool(t, p, func(s ThreadPoolStats) bool { return s.Stuck == 0 })
+}
+
+// 达到 maxStuck 后不再替换 worker，新任务被拒绝
+func TestThreadPoolBulkhead_MaxStuck(t *testing.T) {
+	p := NewThreadPoolBulkhead(1, 0).WithStuckThreshold(10 * time.Millisecond).WithMaxStuck(1)
+	defer p.Close(context.Background())
+
+	release := make(chan struct{})
+	defer close(release)
+
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		_ = p.Execute(ctx, func(context.Context) error {
+			<-release
+			return nil
+		})
+		cancel()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if s := p.Stats(); s.Stuck != 1 || s.Recycled != 1 {
+		t.Fatalf("expected a single replacement, got %+v", s)
+	}
+
+	err := p.Execute(context.Background(), func(ctx context.Context) error { return nil })
+	var rejected *BulkheadRejectedError
+	if !errors.As(err, &rejected) || rejected.Reason != RejectQueueFull {
+		t.Fatalf("expected queue full rejection, got %v", err)
+	}
+}
+
+// 关闭后拒绝新任务
+func TestThreadPoolBulkhead_Close(t *testing.T) {
+	p := NewThreadPoolBulkhead(1, 0)
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	err := p.Execute(context.Background(), func(ctx context.Context) error { return nil })
+	if !errors.Is(err, ErrBulkheadClosed) {
+		t.Fatalf("expected closed rejection, got %v", err)
+	}
+}
+
+// 关闭时拒绝排队任务，并等待执行中的任务结束
+func TestThreadPoolBulkhead_CloseRejectQueued(t *testing.T) {
+	p := NewThreadPoolBulkhead(1, 1)
+
+	release := make(chan struct{})
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			done <- p.Execute(context.Background(), func(ctx context.Context) error {
+				<-release
+				return nil
+			})
+		}()
+		waitPool(t, p, func(s ThreadPoolStats) bool { return s.Busy+s.Queued == i+1 })
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected close to wait for the running task, got %v", err)
+	}
+
+	var rejected *BulkheadRejectedError
+	if err := <-done; !errors.As(err, &rejected) || rejected.Reason != RejectClosed {
+		t.Fatalf("expected queued task rejected, got %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+// CloseDrainQueued 时排队任务照常执行
+func TestThreadPoolBulkhead_CloseDrainQueued(t *testing.T) {
+	p := NewThreadPoolBulkhead(1, 1).WithClosePolicy(CloseDrainQueued)
+
+	release := make(chan struct{})
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			done <- p.Execute(context.Background(), func(ctx context.Context) error {
+				<-release
+				return nil
+			})
+		}()
+		waitPool(t, p, func(s ThreadPoolStats) bool { return s.Busy+s.Queued == i+1 })
+	}
+
+	closed := make(chan error, 1)
+	go func() { closed <- p.Close(context.Background()) }()
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if err := <-closed; err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+// 调用方离开后任务的 panic 通过 OnAbandonedCompleted 上报
+func TestThreadPoolBulkhead_OnAbandonedCompleted(t *testing.T) {
+	reported := make(chan error, 1)
+	p := NewThreadPoolBulkhead(1, 0).OnAbandonedCompleted(func(err error, elapsed time.Duration) {
+		reported <- err
+	})
+	defer p.Close(context.Background())
+
+	release := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := p.Execute(ctx, func(ctx context.Context) error {
+		<-release
+		panic("boom")
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	close(release)
+	var panicErr *PanicError
+	if err := <-reported; !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+		t.Fatalf("expected abandoned panic reported, got %v", err)
+	}
+}
+
+// fn 中的 panic 在调用方 goroutine 上重新抛出
+func TestThreadPoolBulkhead_Panic(t *testing.T) {
+	p := NewThreadPoolBulkhead(1, 0)
+	defer p.Close(context.Background())
+
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Fatalf("expected re-panic, got %v", r)
+		}
+	}()
+	_ = p.Execute(context.Background(), func(ctx context.Context) error { panic("boom") })
+}
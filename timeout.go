@@ -3,6 +3,8 @@ package resilience
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,6 +20,38 @@ const (
 	Pessimistic
 )
 
+func (m TimeoutMode) String() string {
+	switch m {
+	case Optimistic:
+		return "Optimistic"
+	case Pessimistic:
+		return "Pessimistic"
+	default:
+		return "Unknown"
+	}
+}
+
+// TimeoutError reports which Timeout policy fired. It matches ErrTimeout
+// with errors.Is, and is also the cause (see context.Cause) of the context
+// cancelled by that policy.
+type TimeoutError struct {
+	Policy  string        // 策略名称，见 WithName
+	Timeout time.Duration // 配置的超时
+	Elapsed time.Duration // 实际经过的时间
+	Mode    TimeoutMode
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Policy == "" {
+		return fmt.Sprintf("%s after %v (timeout %v, %s)", ErrTimeout, e.Elapsed, e.Timeout, e.Mode)
+	}
+	return fmt.Sprintf("%s: %s after %v (timeout %v, %s)", e.Policy, ErrTimeout, e.Elapsed, e.Timeout, e.Mode)
+}
+
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
 type OnTimeoutFunc func(
 	elapsed time.Duration, // 已过去的时间
 
@@ -25,10 +59,29 @@ type OnTimeoutFunc func(
 
 )
 
+// OnAbandonedCompletedFunc observes a pessimistic execution that completed
+// after the caller stopped waiting for it. 超时后才完成（或 panic）的执行结果。
+type OnAbandonedCompletedFunc func(
+	err error, // fn 的返回值；panic 时为 *PanicError
+	elapsed time.Duration, // 从开始执行到完成的时间
+)
+
+// TimeoutGenerator computes the timeout for a single execution.
+// 返回值 <= 0 表示本次执行不设超时。
+type TimeoutGenerator func(ctx context.Context) time.Duration
+
 type Timeout struct {
+	name      string
 	timeout   time.Duration
+	generator TimeoutGenerator
+	adaptive  *adaptiveTimeout
 	mode      TimeoutMode
 	onTimeout OnTimeoutFunc
+
+	onAbandonedCompleted OnAbandonedCompletedFunc
+
+	recoverPanics bool
+	abandoned     atomic.Int64 // Pessimistic 模式下仍在运行的已放弃 goroutine 数
 }
 
 func NewTimeout(timeout time.Duration) *Timeout {
@@ -38,6 +91,26 @@ func NewTimeout(timeout time.Duration) *Timeout {
 	}
 }
 
+// NewTimeoutFunc creates a timeout policy whose timeout is computed per execution
+func NewTimeoutFunc(generator TimeoutGenerator) *Timeout {
+	return &Timeout{
+		generator: generator,
+		mode:      Optimistic,
+	}
+}
+
+// WithTimeoutGenerator configures a per-execution timeout, overriding the fixed one
+func (t *Timeout) WithTimeoutGenerator(generator TimeoutGenerator) *Timeout {
+	t.generator = generator
+	return t
+}
+
+// WithName names the policy so its TimeoutError can be told apart from others
+func (t *Timeout) WithName(name string) *Timeout {
+	t.name = name
+	return t
+}
+
 func (t *Timeout) WithMode(mode TimeoutMode) *Timeout {
 	t.mode = mode
 	return t
@@ -48,58 +121,196 @@ func (t *Timeout) OnTimeout(fn OnTimeoutFunc) *Timeout {
 	return t
 }
 
+// OnAbandonedCompleted sets the callback for pessimistic executions that
+// complete after being abandoned by a timeout or a cancelled caller.
+func (t *Timeout) OnAbandonedCompleted(fn OnAbandonedCompletedFunc) *Timeout {
+	t.onAbandonedCompleted = fn
+	return t
+}
+
+// Abandoned returns the number of abandoned pessimistic executions still running
+func (t *Timeout) Abandoned() int64 {
+	return t.abandoned.Load()
+}
+
+// RecoverPanics converts panics in fn into a *PanicError, see Recover
+func (t *Timeout) RecoverPanics() *Timeout {
+	t.recoverPanics = true
+	return t
+}
+
 func (t *Timeout) Execute(ctx context.Context, fn Func) error {
+	if t.recoverPanics {
+		fn = recoverFunc(fn)
+	}
+
+	timeout := t.timeoutFor(ctx)
+	if t.adaptive == nil {
+		return t.execute(ctx, fn, timeout)
+	}
+
+	start := time.Now()
+	err := t.execute(ctx, fn, timeout)
+	t.adaptive.observe(err, time.Since(start), timeout)
+	return err
+}
+
+func (t *Timeout) execute(ctx context.Context, fn Func, timeout time.Duration) error {
+	if timeout <= 0 {
+		// 不设超时
+		return fn(ctx)
+	}
+
 	switch t.mode {
 	case Optimistic:
-		return t.executeOptimistic(ctx, fn)
+		return t.executeOptimistic(ctx, fn, timeout)
 	case Pessimistic:
-		return t.executePessimistic(ctx, fn)
+		return t.executePessimistic(ctx, fn, timeout)
 	default:
-		return t.executeOptimistic(ctx, fn)
+		return t.executeOptimistic(ctx, fn, timeout)
 	}
 }
 
-func (t *Timeout) executeOptimistic(ctx context.Context, fn Func) error {
-	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+// timeoutFor resolves the timeout for a single execution
+func (t *Timeout) timeoutFor(ctx context.Context) time.Duration {
+	if t.generator != nil {
+		return t.generator(ctx)
+	}
+	if t.adaptive != nil {
+		return t.adaptive.timeout()
+	}
+	return t.timeout
+}
+
+func (t *Timeout) executeOptimistic(ctx context.Context, fn Func, timeout time.Duration) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	cause := t.newError(timeout, timeout)
+	execCtx, cancel := context.WithTimeoutCause(ctx, timeout, cause)
 	defer cancel()
 
 	start := time.Now()
-	err := fn(ctx)
+	err := fn(execCtx)
 
-	// 1️⃣ 上下文取消优先
-	if errors.Is(ctx.Err(), context.Canceled) {
-		return context.Canceled
+	// 成功的调用不因调用方随后的取消或截止时间而失败，只有本策略的超时例外
+	if err == nil && context.Cause(execCtx) != cause {
+		return nil
+	}
+
+	// 1️⃣ 调用方的取消或截止时间优先，原样返回
+	if ctx.Err() != nil {
+		return ctx.Err()
 	}
 
 	// 2️⃣ 超时
 	if errors.Is(err, context.DeadlineExceeded) ||
-		errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		errors.Is(execCtx.Err(), context.DeadlineExceeded) {
 		t.trigger(start, ctx)
-		return ErrTimeout
+		return t.newError(timeout, time.Since(start))
 	}
 
 	return err
 }
 
-func (t *Timeout) executePessimistic(ctx context.Context, fn Func) error {
-	result := make(chan error, 1)
+func (t *Timeout) executePessimistic(ctx context.Context, fn Func, timeout time.Duration) error {
+	execCtx, cancel := context.WithCancelCause(ctx)
+
+	result := make(chan pessimisticResult, 1)
+	var state atomic.Int32 // pessimisticRunning → pessimisticDone | pessimisticAbandoned
 	start := time.Now()
 
 	go func() {
-		result <- fn(ctx)
+		res := pessimisticResult{}
+		defer func() {
+			if r := recover(); r != nil {
+				res.panic = newPanicError(r)
+			}
+
+			if state.CompareAndSwap(pessimisticRunning, pessimisticDone) {
+				result <- res
+				return
+			}
+
+			// 调用方已放弃，结果只能通过回调观察
+			t.abandoned.Add(-1)
+			if t.onAbandonedCompleted != nil {
+				err := res.err
+				if res.panic != nil {
+					err = res.panic
+				}
+				t.onAbandonedCompleted(err, time.Since(start))
+			}
+		}()
+
+		res.err = fn(execCtx)
 	}()
 
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
 	select {
-	case err := <-result:
-		return err
-	case <-time.After(t.timeout):
+	case res := <-result:
+		cancel(nil)
+		return res.unwrap()
+	case <-timer.C:
+		if !t.abandon(&state, cancel, t.newError(timeout, timeout)) {
+			return (<-result).unwrap()
+		}
 		t.trigger(start, ctx)
-		return ErrTimeout
+		return t.newError(timeout, time.Since(start))
 	case <-ctx.Done():
+		if !t.abandon(&state, cancel, context.Cause(ctx)) {
+			return (<-result).unwrap()
+		}
 		return ctx.Err()
 	}
 }
 
+// abandon gives up on a pessimistic execution and cancels its context.
+// It returns false if the execution completed in the meantime.
+func (t *Timeout) abandon(state *atomic.Int32, cancel context.CancelCauseFunc, cause error) bool {
+	defer cancel(cause)
+
+	// 先计数，避免 goroutine 抢先递减导致计数短暂为负
+	t.abandoned.Add(1)
+	if !state.CompareAndSwap(pessimisticRunning, pessimisticAbandoned) {
+		t.abandoned.Add(-1)
+		return false
+	}
+	return true
+}
+
+const (
+	pessimisticRunning int32 = iota
+	pessimisticDone
+	pessimisticAbandoned
+)
+
+// pessimisticResult carries the outcome of fn from the detached goroutine
+type pessimisticResult struct {
+	err   error
+	panic *PanicError
+}
+
+// unwrap returns the error, re-panicking on the caller's goroutine if fn panicked
+func (r pessimisticResult) unwrap() error {
+	if r.panic != nil {
+		panic(r.panic.Value)
+	}
+	return r.err
+}
+
+func (t *Timeout) newError(timeout, elapsed time.Duration) *TimeoutError {
+	return &TimeoutError{
+		Policy:  t.name,
+		Timeout: timeout,
+		Elapsed: elapsed,
+		Mode:    t.mode,
+	}
+}
+
 func (t *Timeout) trigger(start time.Time, ctx context.Context) {
 	if t.onTimeout != nil {
 		t.onTimeout(time.Since(start), ctx)
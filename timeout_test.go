@@ -158,3 +158,222 @@ func TestTimeout_Pessimistic_OnTimeoutCalled(t *testing.T) {
 		t.Fatalf("expected OnTimeout to be called once")
 	}
 }
+
+type tierKey struct{}
+
+// 超时生成器：按 ctx 决定超时，两种模式均生效
+func TestTimeout_Generator(t *testing.T) {
+	gen := func(ctx context.Context) time.Duration {
+		if tier, _ := ctx.Value(tierKey{}).(string); tier == "gold" {
+			return 100 * time.Millisecond
+		}
+		return 10 * time.Millisecond
+	}
+
+	for _, mode := range []TimeoutMode{Optimistic, Pessimistic} {
+		to := NewTimeoutFunc(gen).WithMode(mode)
+
+		fn := func(ctx context.Context) error {
+			select {
+			case <-time.After(30 * time.Millisecond):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if err := to.Execute(context.Background(), fn); !errors.Is(err, ErrTimeout) {
+			t.Fatalf("mode %d: expected ErrTimeout, got %v", mode, err)
+		}
+
+		gold := context.WithValue(context.Background(), tierKey{}, "gold")
+		if err := to.Execute(gold, fn); err != nil {
+			t.Fatalf("mode %d: unexpected error: %v", mode, err)
+		}
+	}
+}
+
+// 超时生成器返回 <= 0 表示不设超时
+func TestTimeout_Generator_NoTimeout(t *testing.T) {
+	to := NewTimeout(time.Nanosecond).
+		WithTimeoutGenerator(func(ctx context.Context) time.Duration { return 0 })
+
+	err := to.Execute(context.Background(), func(ctx context.Context) error {
+		time.Sleep(5 * time.Millisecond)
+		if _, ok := ctx.Deadline(); ok {
+			return errors.New("unexpected deadline")
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Pessimistic：超时后取消派生 context，并统计被放弃的 goroutine
+func TestTimeout_Pessimistic_CancelsAbandoned(t *testing.T) {
+	completed := make(chan error, 1)
+	release := make(chan struct{})
+
+	to := NewTimeout(10 * time.Millisecond).
+		WithMode(Pessimistic).
+		OnAbandonedCompleted(func(err error, elapsed time.Duration) {
+			completed <- err
+		})
+
+	err := to.Execute(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		<-release
+		return ctx.Err()
+	})
+
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if n := to.Abandoned(); n != 1 {
+		t.Fatalf("expected 1 abandoned execution, got %d", n)
+	}
+
+	close(release)
+
+	select {
+	case err := <-completed:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled from abandoned work, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("OnAbandonedCompleted not called")
+	}
+
+	if n := to.Abandoned(); n != 0 {
+		t.Fatalf("expected 0 abandoned executions, got %d", n)
+	}
+}
+
+// Pessimistic：被放弃的执行 panic 不会导致进程崩溃
+func TestTimeout_Pessimistic_AbandonedPanic(t *testing.T) {
+	completed := make(chan error, 1)
+
+	to := NewTimeout(5 * time.Millisecond).
+		WithMode(Pessimistic).
+		OnAbandonedCompleted(func(err error, elapsed time.Duration) {
+			completed <- err
+		})
+
+	err := to.Execute(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		panic("boom")
+	})
+
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+
+	select {
+	case err := <-completed:
+		if err == nil {
+			t.Fatalf("expected panic error")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("OnAbandonedCompleted not called")
+	}
+}
+
+// TimeoutError 标识触发超时的策略，并作为 context 的取消原因
+func TestTimeout_TimeoutError_IdentifiesPolicy(t *testing.T) {
+	outer := NewTimeout(200 * time.Millisecond).WithName("outer")
+	inner := NewTimeout(10 * time.Millisecond).WithName("inner")
+
+	var cause error
+	err := Wrap(outer, inner).Execute(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		return ctx.Err()
+	})
+
+	var te *TimeoutError
+	if !errors.As(err, &te) || !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected TimeoutError, got %v", err)
+	}
+	if te.Policy != "inner" || te.Timeout != 10*time.Millisecond || te.Mode != Optimistic {
+		t.Fatalf("unexpected TimeoutError: %+v", te)
+	}
+	if te.Elapsed < te.Timeout {
+		t.Fatalf("expected elapsed >= timeout, got %v", te.Elapsed)
+	}
+
+	var ce *TimeoutError
+	if !errors.As(cause, &ce) || ce.Policy != "inner" {
+		t.Fatalf("expected inner TimeoutError as context cause, got %v", cause)
+	}
+}
+
+// Optimistic：调用方截止时间原样返回，不被当作本策略超时
+func TestTimeout_Optimistic_CallerDeadline(t *testing.T) {
+	to := NewTimeout(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := to.Execute(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected caller's context.DeadlineExceeded, got %v", err)
+	}
+}
+
+// Pessimistic：被取消的执行可以通过 context.Cause 看到超时原因
+func TestTimeout_Pessimistic_Cause(t *testing.T) {
+	causes := make(chan error, 1)
+
+	to := NewTimeout(10 * time.Millisecond).
+		WithName("db").
+		WithMode(Pessimistic)
+
+	err := to.Execute(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return ctx.Err()
+	})
+
+	var te *TimeoutError
+	if !errors.As(err, &te) || te.Mode != Pessimistic || te.Policy != "db" {
+		t.Fatalf("expected pessimistic TimeoutError, got %v", err)
+	}
+
+	if cause := <-causes; !errors.Is(cause, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout cause, got %v", cause)
+	}
+}
+
+// 乐观模式：fn 成功后调用方 ctx 才结束，仍返回成功
+func TestTimeout_OptimisticSuccessBeforeCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := NewTimeout(time.Second).Execute(ctx, func(context.Context) error {
+		cancel()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+}
+
+// 乐观模式：fn 成功后调用方截止时间才到达，仍返回成功
+func TestTimeout_OptimisticSuccessBeforeCallerDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := NewTimeout(time.Second).Execute(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrNoResult is returned by ExecuteT when the policy reports success but
+// no value of type T was produced, e.g. an untyped NewFallback or a
+// NewFallbackT of another type recovered the failure.
+var ErrNoResult = errors.New("execution succeeded without a result")
+
+// FuncT is the typed form of Func, returning a value alongside the error
+type FuncT[T any] func(ctx context.Context) (T, error)
+
+type resultKey[T any] struct{}
+
+// result carries the value of a typed execution through the policies.
+// Abandoned executions (pessimistic timeout) may still write to it, hence
+// the lock.
+type result[T any] struct {
+	mutex sync.Mutex
+	value T
+	set   bool
+}
+
+// ExecuteT runs fn through policy and returns its value. Policies that
+// supply a substitute value, such as NewFallbackT, replace it on the way
+// out. 出错时返回 T 的零值。
+//
+// If the policy succeeds without fn or a typed fallback of the same T
+// supplying a value, ExecuteT returns ErrNoResult rather than a silent
+// zero value.
+func ExecuteT[T any](ctx context.Context, policy Resilience, fn FuncT[T]) (T, error) {
+	res := &result[T]{}
+	ctx = context.WithValue(ctx, resultKey[T]{}, res)
+
+	err := policy.Execute(ctx, func(ctx context.Context) error {
+		value, err := fn(ctx)
+		if err == nil {
+			setResult(ctx, value)
+		}
+		return err
+	})
+
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	value, ok := res.get()
+	if !ok {
+		return value, ErrNoResult
+	}
+	return value, nil
+}
+
+// setResult stores value for the enclosing ExecuteT, if any
+func setResult[T any](ctx context.Context, value T) {
+	res, ok := ctx.Value(resultKey[T]{}).(*result[T])
+	if !ok {
+		return
+	}
+
+	res.mutex.Lock()
+	res.value, res.set = value, true
+	res.mutex.Unlock()
+}
+
+// get returns the stored value and whether one was stored
+func (r *result[T]) get() (T, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.value, r.set
+}
@@ -0,0 +1,316 @@
+package resilience
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// WindowAlgorithm selects how a WindowLimiter counts calls
+type WindowAlgorithm int
+
+const (
+	// FixedWindow counts calls in aligned windows; cheap, but allows up to
+	// twice the limit around a window boundary
+	FixedWindow WindowAlgorithm = iota
+
+	// SlidingWindowLog keeps the timestamp of every call in the last window;
+	// exact, memory grows with the limit
+	SlidingWindowLog
+
+	// SlidingWindowCounter weights the previous window's count by its overlap
+	// with the sliding window; a close approximation in constant memory
+	SlidingWindowCounter
+)
+
+func (a WindowAlgorithm) String() string {
+	switch a {
+	case FixedWindow:
+		return "FixedWindow"
+	case SlidingWindowLog:
+		return "SlidingWindowLog"
+	case SlidingWindowCounter:
+		return "SlidingWindowCounter"
+	default:
+		return "Unknown"
+	}
+}
+
+// windowEntry is a call recorded by SlidingWindowLog
+type windowEntry struct {
+	at   time.Time
+	cost int
+}
+
+// windowState is the state of a single key
+type windowState struct {
+	key      string
+	lastUsed time.Time
+
+	start time.Time // 当前窗口的起点
+	count int       // 当前窗口内的计数
+	prev  int       // 上一个窗口的计数（SlidingWindowCounter）
+
+	log []windowEntry // 最近一个窗口内的调用（SlidingWindowLog）
+}
+
+// WindowLimiter implements the Resilience interface. It allows limit calls
+// per window for each key, e.g. a per-customer quota. Keys are taken from
+// PartitionKeyFromContext unless WithKeyFunc is used. Memory is bounded by
+// evicting the least recently used key beyond maxKeys and keys idle for
+// longer than the idle timeout.
+type WindowLimiter struct {
+	algorithm   WindowAlgorithm
+	limit       int           // 每个窗口每个 key 允许的调用数
+	window      time.Duration // 窗口长度
+	maxKeys     int           // 最多跟踪的 key 数，默认 10000
+	idleTimeout time.Duration // 空闲 key 的回收时间，默认两个窗口
+
+	keyFunc PartitionKeyFunc
+	weigher func(ctx context.Context) int // cost of a call, see WithWeigher
+
+	mutex sync.Mutex
+	keys  map[string]*list.Element // of *windowState
+	lru   list.List                // most recently used at the front
+
+	onRejected OnRateLimitedFunc
+
+	recoverPanics bool
+}
+
+// NewFixedWindowLimiter creates a fixed-window rate limiter policy
+func NewFixedWindowLimiter(limit int, window time.Duration) *WindowLimiter {
+	return newWindowLimiter(FixedWindow, limit, window)
+}
+
+// NewSlidingWindowLogLimiter creates a sliding-window-log rate limiter policy
+func NewSlidingWindowLogLimiter(limit int, window time.Duration) *WindowLimiter {
+	return newWindowLimiter(SlidingWindowLog, limit, window)
+}
+
+// NewSlidingWindowCounterLimiter creates a sliding-window-counter rate
+// limiter policy
+func NewSlidingWindowCounterLimiter(limit int, window time.Duration) *WindowLimiter {
+	return newWindowLimiter(SlidingWindowCounter, limit, window)
+}
+
+func newWindowLimiter(algorithm WindowAlgorithm, limit int, window time.Duration) *WindowLimiter {
+	if limit <= 0 {
+		panic("limit must be > 0")
+	}
+	if window <= 0 {
+		panic("window must be > 0")
+	}
+
+	return &WindowLimiter{
+		algorithm:   algorithm,
+		limit:       limit,
+		window:      window,
+		maxKeys:     10000,
+		idleTimeout: 2 * window,
+		keyFunc:     PartitionKeyFromContext,
+		keys:        make(map[string]*list.Element),
+	}
+}
+
+// WithKeyFunc configures how the partition key is extracted from ctx
+func (w *WindowLimiter) WithKeyFunc(fn PartitionKeyFunc) *WindowLimiter {
+	w.keyFunc = fn
+	return w
+}
+
+// WithMaxKeys bounds the number of tracked keys. Beyond it the least
+// recently used key is forgotten, which resets its quota.
+func (w *WindowLimiter) WithMaxKeys(n int) *WindowLimiter {
+	if n <= 0 {
+		panic("maxKeys must be > 0")
+	}
+	w.maxKeys = n
+	return w
+}
+
+// WithIdleTimeout configures when an idle key is removed. Values shorter
+// than two windows would reset quotas early and are raised to two windows.
+func (w *WindowLimiter) WithIdleTimeout(d time.Duration) *WindowLimiter {
+	if d < 2*w.window {
+		d = 2 * w.window
+	}
+	w.idleTimeout = d
+	return w
+}
+
+// WithWeigher configures how much of the limit a call consumes, overriding
+// WithCost. Calls costing more than limit can never be admitted and are
+// rejected with a permanent error matching ErrRateLimited.
+func (w *WindowLimiter) WithWeigher(fn func(ctx context.Context) int) *WindowLimiter {
+	w.weigher = fn
+	return w
+}
+
+// OnRejected sets the callback for rejected calls
+func (w *WindowLimiter) OnRejected(fn OnRateLimitedFunc) *WindowLimiter {
+	w.onRejected = fn
+	return w
+}
+
+// RecoverPanics converts panics in fn into a *PanicError, see Recover
+func (w *WindowLimiter) RecoverPanics() *WindowLimiter {
+	w.recoverPanics = true
+	return w
+}
+
+// Keys returns the number of tracked keys
+func (w *WindowLimiter) Keys() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.lru.Len()
+}
+
+func (w *WindowLimiter) Execute(ctx context.Context, fn Func) error {
+	if w.recoverPanics {
+		fn = recoverFunc(fn)
+	}
+
+	cost := w.costFor(ctx)
+	if cost > w.limit {
+		return errCostTooHigh(cost, w.limit)
+	}
+	key := w.keyFunc(ctx)
+	now := time.Now()
+
+	w.mutex.Lock()
+	w.evictIdleLocked(now)
+	state := w.stateLocked(key, now)
+
+	var retryAfter time.Duration
+	var ok bool
+	switch w.algorithm {
+	case SlidingWindowLog:
+		retryAfter, ok = w.allowLogLocked(state, now, cost)
+	case SlidingWindowCounter:
+		retryAfter, ok = w.allowCounterLocked(state, now, cost)
+	default:
+		retryAfter, ok = w.allowFixedLocked(state, now, cost)
+	}
+	w.mutex.Unlock()
+
+	if !ok {
+		if w.onRejected != nil {
+			w.onRejected(retryAfter, ctx)
+		}
+		return &RateLimitedError{RetryAfter: retryAfter}
+	}
+	return fn(ctx)
+}
+
+func (w *WindowLimiter) allowFixedLocked(s *windowState, now time.Time, cost int) (time.Duration, bool) {
+	if start := now.Truncate(w.window); !start.Equal(s.start) {
+		s.start, s.count = start, 0
+	}
+
+	if s.count+cost > w.limit {
+		return s.start.Add(w.window).Sub(now), false
+	}
+	s.count += cost
+	return 0, true
+}
+
+func (w *WindowLimiter) allowLogLocked(s *windowState, now time.Time, cost int) (time.Duration, bool) {
+	// 丢弃窗口之外的记录
+	cutoff := now.Add(-w.window)
+	expired := 0
+	for expired < len(s.log) && !s.log[expired].at.After(cutoff) {
+		s.count -= s.log[expired].cost
+		expired++
+	}
+	s.log = append(s.log[:0], s.log[expired:]...)
+
+	if s.count+cost > w.limit {
+		// 等到足够多的旧记录过期
+		excess := s.count + cost - w.limit
+		for _, e := range s.log {
+			excess -= e.cost
+			if excess <= 0 {
+				return e.at.Add(w.window).Sub(now), false
+			}
+		}
+		return w.window, false
+	}
+
+	s.log = append(s.log, windowEntry{at: now, cost: cost})
+	s.count += cost
+	return 0, true
+}
+
+func (w *WindowLimiter) allowCounterLocked(s *windowState, now time.Time, cost int) (time.Duration, bool) {
+	start := now.Truncate(w.window)
+	if !start.Equal(s.start) {
+		if start.Sub(s.start) == w.window {
+			s.prev = s.count
+		} else {
+			s.prev = 0
+		}
+		s.start, s.count = start, 0
+	}
+
+	// 上一个窗口按与滑动窗口的重叠比例计入
+	elapsed := float64(now.Sub(start)) / float64(w.window)
+	estimate := float64(s.prev)*(1-elapsed) + float64(s.count)
+
+	if estimate+float64(cost) > float64(w.limit) {
+		room := w.limit - s.count - cost
+		if room < 0 || s.prev == 0 {
+			return start.Add(w.window).Sub(now), false
+		}
+		// 上一个窗口的权重降到 room/prev 时可以放行
+		until := time.Duration((1 - float64(room)/float64(s.prev)) * float64(w.window))
+		return start.Add(until).Sub(now), false
+	}
+	s.count += cost
+	return 0, true
+}
+
+// stateLocked returns the state of key, creating it and evicting the least
+// recently used key if needed
+func (w *WindowLimiter) stateLocked(key string, now time.Time) *windowState {
+	if elem, ok := w.keys[key]; ok {
+		w.lru.MoveToFront(elem)
+		s := elem.Value.(*windowState)
+		s.lastUsed = now
+		return s
+	}
+
+	s := &windowState{key: key, lastUsed: now}
+	w.keys[key] = w.lru.PushFront(s)
+	if w.lru.Len() > w.maxKeys {
+		oldest := w.lru.Remove(w.lru.Back()).(*windowState)
+		delete(w.keys, oldest.key)
+	}
+	return s
+}
+
+// evictIdleLocked removes keys idle for longer than idleTimeout. The LRU
+// list is ordered by last use, so only its tail needs checking.
+func (w *WindowLimiter) evictIdleLocked(now time.Time) {
+	for elem := w.lru.Back(); elem != nil; elem = w.lru.Back() {
+		s := elem.Value.(*windowState)
+		if now.Sub(s.lastUsed) < w.idleTimeout {
+			return
+		}
+		w.lru.Remove(elem)
+		delete(w.keys, s.key)
+	}
+}
+
+// costFor returns how much of the limit a call consumes
+func (w *WindowLimiter) costFor(ctx context.Context) int {
+	n := CostFromContext(ctx)
+	if w.weigher != nil {
+		n = w.weigher(ctx)
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
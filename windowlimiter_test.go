@@ -0,0 +1,132 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func keyed(key string) context.Context {
+	return WithPartitionKey(context.Background(), key)
+}
+
+// 每个 key 独立计数，超出配额被拒绝
+func TestWindowLimiter_PerKey(t *testing.T) {
+	for _, newLimiter := range []func(int, time.Duration) *WindowLimiter{
+		NewFixedWindowLimiter,
+		NewSlidingWindowLogLimiter,
+		NewSlidingWindowCounterLimiter,
+	} {
+		wl := newLimiter(3, time.Hour)
+		t.Run(wl.algorithm.String(), func(t *testing.T) {
+			for i := 0; i < 3; i++ {
+				if err := wl.Execute(keyed("a"), noop); err != nil {
+					t.Fatalf("call %d: unexpected error %v", i, err)
+				}
+			}
+
+			err := wl.Execute(keyed("a"), noop)
+			var limited *RateLimitedError
+			if !errors.As(err, &limited) || limited.RetryAfter <= 0 || limited.RetryAfter > time.Hour {
+				t.Fatalf("expected rate limited with retry-after, got %v", err)
+			}
+
+			if err := wl.Execute(keyed("b"), noop); err != nil {
+				t.Fatalf("other key should have its own quota, got %v", err)
+			}
+		})
+	}
+}
+
+// 滑动窗口日志：旧记录过期后恢复配额
+func TestWindowLimiter_SlidingLogExpires(t *testing.T) {
+	wl := NewSlidingWindowLogLimiter(2, 50*time.Millisecond)
+
+	_ = wl.Execute(keyed("a"), noop)
+	time.Sleep(30 * time.Millisecond)
+	_ = wl.Execute(keyed("a"), noop)
+
+	err := wl.Execute(keyed("a"), noop)
+	var limited *RateLimitedError
+	if !errors.As(err, &limited) {
+		t.Fatalf("expected rate limited, got %v", err)
+	}
+	// 只需等待第一条记录过期
+	if limited.RetryAfter > 25*time.Millisecond {
+		t.Fatalf("expected retry-after until the oldest entry expires, got %v", limited.RetryAfter)
+	}
+
+	time.Sleep(limited.RetryAfter + 5*time.Millisecond)
+	if err := wl.Execute(keyed("a"), noop); err != nil {
+		t.Fatalf("expected quota after expiry, got %v", err)
+	}
+}
+
+// 滑动窗口计数：上一个窗口的计数按比例计入
+func TestWindowLimiter_SlidingCounterWeightsPrevious(t *testing.T) {
+	wl := NewSlidingWindowCounterLimiter(10, time.Minute)
+	now := time.Now().Truncate(time.Minute)
+	s := &windowState{}
+
+	for i := 0; i < 10; i++ {
+		if _, ok := wl.allowCounterLocked(s, now.Add(time.Second), 1); !ok {
+			t.Fatalf("call %d should be allowed", i)
+		}
+	}
+
+	// 下一个窗口过去 1/4 时，上一个窗口仍计入 7.5
+	next := now.Add(time.Minute + 15*time.Second)
+	for i := 0; i < 2; i++ {
+		if _, ok := wl.allowCounterLocked(s, next, 1); !ok {
+			t.Fatalf("call %d should be allowed", i)
+		}
+	}
+	retryAfter, ok := wl.allowCounterLocked(s, next, 1)
+	if ok {
+		t.Fatal("expected estimate to exceed the limit")
+	}
+	// 权重降到 0.7 时可放行：窗口开始后 18s
+	if retryAfter != 3*time.Second {
+		t.Fatalf("expected retry-after 3s, got %v", retryAfter)
+	}
+}
+
+// 加权调用
+func TestWindowLimiter_Cost(t *testing.T) {
+	wl := NewFixedWindowLimiter(5, time.Hour)
+	if err := wl.Execute(WithCost(keyed("a"), 4), noop); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := wl.Execute(WithCost(keyed("a"), 2), noop); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected rate limited, got %v", err)
+	}
+
+	// 超过窗口上限的调用直接以永久错误拒绝，不占用配额
+	err := wl.Execute(WithCost(keyed("b"), 6), noop)
+	if !errors.Is(err, ErrRateLimited) || !IsPermanent(err) {
+		t.Fatalf("expected permanent rate limited error, got %v", err)
+	}
+	if err := wl.Execute(WithCost(keyed("b"), 5), noop); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+// key 数量受限，按 LRU 淘汰；空闲 key 被回收
+func TestWindowLimiter_BoundedKeys(t *testing.T) {
+	wl := NewFixedWindowLimiter(1, 10*time.Millisecond).WithMaxKeys(3)
+
+	for i := 0; i < 5; i++ {
+		_ = wl.Execute(keyed(strconv.Itoa(i)), noop)
+	}
+	if n := wl.Keys(); n != 3 {
+		t.Fatalf("expected 3 keys, got %d", n)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	_ = wl.Execute(keyed("fresh"), noop)
+	if n := wl.Keys(); n != 1 {
+		t.Fatalf("expected idle keys evicted, got %d", n)
+	}
+}